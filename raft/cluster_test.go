@@ -3,16 +3,19 @@ package raft
 import (
 	"bytes"
 	"context"
+	"encoding/gob"
 	"log"
 	"net"
 	"runtime"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/justin0u0/raft/pb"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
 )
 
 // consumer consumes logs that are commited from the applyCh
@@ -40,10 +43,40 @@ func (c *consumer) start(ctx context.Context) {
 			c.mu.Lock()
 			c.logs[e.Id] = e
 			c.mu.Unlock()
+
+		case s := <-c.raft.SnapshotCh():
+			logs := make(map[uint64]*pb.Entry)
+			if err := gob.NewDecoder(bytes.NewBuffer(s.Data)).Decode(&logs); err != nil {
+				log.Fatal("fail to decode snapshot:", err)
+			}
+
+			c.mu.Lock()
+			c.logs = logs
+			c.mu.Unlock()
 		}
 	}
 }
 
+// snapshot encodes all logs up to and including the given log id
+func (c *consumer) snapshot(id uint64) []byte {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	logs := make(map[uint64]*pb.Entry)
+	for logId, l := range c.logs {
+		if logId <= id {
+			logs[logId] = l
+		}
+	}
+
+	buf := bytes.Buffer{}
+	if err := gob.NewEncoder(&buf).Encode(logs); err != nil {
+		log.Fatal("fail to encode snapshot:", err)
+	}
+
+	return buf.Bytes()
+}
+
 func (c *consumer) getLog(id uint64) *pb.Entry {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -62,9 +95,20 @@ type cluster struct {
 	cancelFuncs map[uint32]context.CancelFunc
 	consumers   map[uint32]*consumer
 	persisters  map[uint32]Persister
+	joiners     map[uint32]bool
+	// rejections are the numbers of AppendEntries rejected by each server
+	rejections map[uint32]*int64
+	// gates block requests to each server
+	gates map[uint32]*gate
+	// configure modifies the config of each server
+	configure func(config *Config)
 }
 
 func newCluster(t *testing.T, numNodes int) *cluster {
+	return newClusterWithConfig(t, numNodes, nil)
+}
+
+func newClusterWithConfig(t *testing.T, numNodes int, configure func(config *Config)) *cluster {
 	c := cluster{
 		t:           t,
 		numNodes:    numNodes,
@@ -74,6 +118,10 @@ func newCluster(t *testing.T, numNodes int) *cluster {
 		cancelFuncs: make(map[uint32]context.CancelFunc),
 		consumers:   make(map[uint32]*consumer),
 		persisters:  make(map[uint32]Persister),
+		joiners:     make(map[uint32]bool),
+		rejections:  make(map[uint32]*int64),
+		gates:       make(map[uint32]*gate),
+		configure:   configure,
 	}
 
 	logger, err := zap.NewDevelopment()
@@ -83,6 +131,11 @@ func newCluster(t *testing.T, numNodes int) *cluster {
 
 	c.logger = logger
 
+	// listen before initializing, so the initial configuration has addresses of all servers
+	for i := 1; i <= numNodes; i++ {
+		c.listen(uint32(i))
+	}
+
 	for i := 1; i <= numNodes; i++ {
 		id := uint32(i)
 		c.initialize(id)
@@ -101,10 +154,8 @@ func newCluster(t *testing.T, numNodes int) *cluster {
 	return &c
 }
 
-// initialize initializes raft and the raft RPC server
-func (c *cluster) initialize(serverId uint32) {
-	c.logger.Debug("initializing raft", zap.Uint32("id", serverId))
-
+// listen setups the listener of the raft RPC server
+func (c *cluster) listen(serverId uint32) {
 	lis, err := net.Listen("tcp", ":0")
 	if err != nil {
 		c.t.Fatal("fail to setup network", err)
@@ -114,19 +165,35 @@ func (c *cluster) initialize(serverId uint32) {
 	c.logger.Debug("setup listner",
 		zap.Uint32("id", serverId),
 		zap.String("addr", c.listerers[serverId].Addr().String()))
+}
+
+// initialize initializes raft and the raft RPC server
+func (c *cluster) initialize(serverId uint32) {
+	c.logger.Debug("initializing raft", zap.Uint32("id", serverId))
+
+	// the listener is closed once the server stops, so a restarted server listens again
+	if _, restarted := c.servers[serverId]; restarted || c.listerers[serverId] == nil {
+		c.listen(serverId)
+	}
 
 	// initialized peers without connection
 	peers := make(map[uint32]Peer)
 	for i := 1; i <= c.numNodes; i++ {
 		peerId := uint32(i)
-		if serverId != peerId {
-			peers[peerId] = &peer{}
+		if serverId == peerId {
+			continue
+		}
+
+		p := &peer{}
+		if lis := c.listerers[peerId]; lis != nil {
+			p.addr = lis.Addr().String()
 		}
+		peers[peerId] = p
 	}
 
 	persister := c.persisters[serverId]
 	if persister == nil {
-		persister = newPersister()
+		persister = NewMemoryPersister()
 		c.persisters[serverId] = persister
 	}
 
@@ -134,15 +201,40 @@ func (c *cluster) initialize(serverId uint32) {
 		HeartbeatTimeout:  150 * time.Millisecond,
 		ElectionTimeout:   150 * time.Millisecond,
 		HeartbeatInterval: 50 * time.Millisecond,
+		Join:              c.joiners[serverId],
+		NewPeer: func(id uint32, address string) (Peer, error) {
+			p := &peer{}
+			if err := p.dial(address, grpc.WithInsecure()); err != nil {
+				return nil, err
+			}
+
+			return p, nil
+		},
+	}
+
+	if c.configure != nil {
+		c.configure(config)
 	}
 
-	raft := NewRaft(serverId, peers, persister, config, c.logger)
+	raft := NewRaft(serverId, peers, persister, nil, config, c.logger)
 	c.rafts[serverId] = raft
 
 	consumer := newConsumer(raft)
 	c.consumers[serverId] = consumer
 
-	grpcServer := grpc.NewServer()
+	rejections := c.rejections[serverId]
+	if rejections == nil {
+		rejections = new(int64)
+		c.rejections[serverId] = rejections
+	}
+
+	g := c.gates[serverId]
+	if g == nil {
+		g = &gate{}
+		c.gates[serverId] = g
+	}
+
+	grpcServer := grpc.NewServer(interceptors(rejections, g)...)
 	pb.RegisterRaftServer(grpcServer, raft)
 	c.servers[serverId] = grpcServer
 
@@ -182,9 +274,9 @@ func (c *cluster) stop(serverId uint32) {
 		return
 	}
 
-	c.servers[serverId].GracefulStop()
 	cancel := c.cancelFuncs[serverId]
 	cancel()
+	c.servers[serverId].GracefulStop()
 
 	c.cancelFuncs[serverId] = nil
 	c.rafts[serverId] = nil
@@ -192,19 +284,26 @@ func (c *cluster) stop(serverId uint32) {
 	c.servers[serverId] = nil
 }
 
+// join initializes and starts a new raft server that waits to be added to the cluster
+func (c *cluster) join(serverId uint32) {
+	c.numNodes++
+	c.joiners[serverId] = true
+
+	c.initialize(serverId)
+	c.connectAll(serverId)
+	c.start(serverId)
+}
+
 // connect connects server to all peers
 func (c *cluster) connectAll(serverId uint32) {
-	peers := c.rafts[serverId].peers
-
-	for peerId := range peers {
+	for _, peerId := range c.peerIds(serverId) {
 		c.connect(serverId, peerId)
 	}
 }
 
 // connect connects server to peer
 func (c *cluster) connect(serverId, peerId uint32) {
-	peers := c.rafts[serverId].peers
-	peer := peers[peerId].(*peer)
+	peer := c.peer(serverId, peerId)
 
 	addr := c.listerers[peerId].Addr().String()
 
@@ -220,8 +319,7 @@ func (c *cluster) connect(serverId, peerId uint32) {
 
 // disconnect disconnects connection from server to peer
 func (c *cluster) disconnect(serverId, peerId uint32) {
-	peers := c.rafts[serverId].peers
-	peer := peers[peerId].(*peer)
+	peer := c.peer(serverId, peerId)
 
 	c.logger.Debug("disconnect server with peer",
 		zap.Uint32("server", serverId),
@@ -234,13 +332,35 @@ func (c *cluster) disconnect(serverId, peerId uint32) {
 
 // disconnectAll disconnects all connections from server to all its peers
 func (c *cluster) disconnectAll(serverId uint32) {
-	peers := c.rafts[serverId].peers
-
-	for peerId := range peers {
+	for _, peerId := range c.peerIds(serverId) {
 		c.disconnect(serverId, peerId)
 	}
 }
 
+// peer gets the peer of the server, peers may be created by raft when the configuration changes
+func (c *cluster) peer(serverId, peerId uint32) *peer {
+	raft := c.rafts[serverId]
+
+	raft.mu.Lock()
+	defer raft.mu.Unlock()
+
+	return raft.peers[peerId].(*peer)
+}
+
+func (c *cluster) peerIds(serverId uint32) []uint32 {
+	raft := c.rafts[serverId]
+
+	raft.mu.Lock()
+	defer raft.mu.Unlock()
+
+	peerIds := make([]uint32, 0, len(raft.peers))
+	for peerId := range raft.peers {
+		peerIds = append(peerIds, peerId)
+	}
+
+	return peerIds
+}
+
 // checkSingleLeader checks if there is only one leader
 // and returns the leader's ID and the leader's term
 func (c *cluster) checkSingleLeader() (uint32, uint64) {
@@ -268,6 +388,26 @@ func (c *cluster) checkSingleLeader() (uint32, uint64) {
 	return leaderId, leaderTerm
 }
 
+// waitSingleLeader waits for only one leader within the timeout, and returns the leader's ID and the leader's term
+func (c *cluster) waitSingleLeader(timeout time.Duration) (uint32, uint64) {
+	for deadline := time.Now().Add(timeout); time.Now().Before(deadline); time.Sleep(100 * time.Millisecond) {
+		leaders := 0
+		for _, raft := range c.rafts {
+			raft.mu.Lock()
+			if raft.state == Leader {
+				leaders++
+			}
+			raft.mu.Unlock()
+		}
+
+		if leaders == 1 {
+			break
+		}
+	}
+
+	return c.checkSingleLeader()
+}
+
 // getCurrentLeader returns the leader with the greatest term
 func (c *cluster) getCurrentLeader() (uint32, uint64) {
 	var leaderId uint32
@@ -315,9 +455,57 @@ func (c *cluster) applyCommand(id uint32, term uint64, data []byte) uint64 {
 	return resp.Entry.GetId()
 }
 
+func (c *cluster) addServer(leaderId, serverId uint32, suffrage pb.Suffrage) {
+	ctx := context.Background()
+	addr := c.listerers[serverId].Addr().String()
+
+	req := &pb.AddServerRequest{Id: serverId, Address: addr, Suffrage: suffrage}
+	if _, err := c.rafts[leaderId].AddServer(ctx, req); err != nil {
+		c.t.Fatal("fail to add server:", err)
+	}
+}
+
+func (c *cluster) promoteLearner(leaderId, serverId uint32) {
+	ctx := context.Background()
+
+	if _, err := c.rafts[leaderId].PromoteLearner(ctx, &pb.PromoteLearnerRequest{Id: serverId}); err != nil {
+		c.t.Fatal("fail to promote learner:", err)
+	}
+}
+
+func (c *cluster) removeServer(leaderId, serverId uint32) {
+	ctx := context.Background()
+
+	if _, err := c.rafts[leaderId].RemoveServer(ctx, &pb.RemoveServerRequest{Id: serverId}); err != nil {
+		c.t.Fatal("fail to remove server:", err)
+	}
+}
+
+func (c *cluster) transferLeadership(leaderId, serverId uint32) {
+	ctx := context.Background()
+
+	if _, err := c.rafts[leaderId].TransferLeadership(ctx, &pb.TransferLeadershipRequest{Id: serverId}); err != nil {
+		c.t.Fatal("fail to transfer leadership:", err)
+	}
+}
+
+func (c *cluster) snapshot(id uint32, logId uint64) {
+	ctx := context.Background()
+
+	if err := c.rafts[id].Snapshot(ctx, logId, c.consumers[id].snapshot(logId)); err != nil {
+		c.t.Fatal("fail to snapshot:", err)
+	}
+}
+
 func (c *cluster) checkLog(serverId uint32, logId uint64, term uint64, data []byte) {
 	l := c.consumers[serverId].getLog(logId)
 
+	// the consumer records the log after receiving it from ApplyCh, which may be after ApplyCommand returns
+	for deadline := time.Now().Add(100 * time.Millisecond); l == nil && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+		l = c.consumers[serverId].getLog(logId)
+	}
+
 	if l == nil {
 		c.t.Fatalf("log %d at server %d is not commited", logId, serverId)
 	}
@@ -330,8 +518,110 @@ func (c *cluster) checkLog(serverId uint32, logId uint64, term uint64, data []by
 	}
 }
 
+// block holds requests to the server until unblock is called
+func (c *cluster) block(serverId uint32) {
+	c.gates[serverId].block()
+}
+
+func (c *cluster) unblock(serverId uint32) {
+	c.gates[serverId].unblock()
+}
+
+// getRejections gets the number of AppendEntries rejected by the server
+func (c *cluster) getRejections(serverId uint32) int64 {
+	return atomic.LoadInt64(c.rejections[serverId])
+}
+
 func (c *cluster) warnNumberOfCPUs() {
 	if runtime.NumCPU() < 2 {
 		c.logger.Warn("number of CPUs < 2, may not test race condition of Raft algorithm")
 	}
 }
+
+// interceptors count AppendEntries rejected by the server, including those streamed by AppendEntriesPipeline,
+// and hold requests to the server while the gate is blocked
+func interceptors(counter *int64, g *gate) []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			if err := g.wait(ctx); err != nil {
+				return nil, status.FromContextError(err).Err()
+			}
+
+			resp, err := handler(ctx, req)
+			if resp, ok := resp.(*pb.AppendEntriesResponse); ok && !resp.GetSuccess() {
+				atomic.AddInt64(counter, 1)
+			}
+
+			return resp, err
+		}),
+		grpc.StreamInterceptor(func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			return handler(srv, &interceptedStream{ServerStream: ss, counter: counter, gate: g})
+		}),
+	}
+}
+
+type interceptedStream struct {
+	grpc.ServerStream
+	counter *int64
+	gate    *gate
+}
+
+func (s *interceptedStream) RecvMsg(m interface{}) error {
+	if err := s.gate.wait(s.Context()); err != nil {
+		return status.FromContextError(err).Err()
+	}
+
+	return s.ServerStream.RecvMsg(m)
+}
+
+func (s *interceptedStream) SendMsg(m interface{}) error {
+	if resp, ok := m.(*pb.AppendEntriesResponse); ok && !resp.GetSuccess() {
+		atomic.AddInt64(s.counter, 1)
+	}
+
+	return s.ServerStream.SendMsg(m)
+}
+
+// gate holds requests to a server while it is blocked, as if the server is slow
+type gate struct {
+	mu sync.Mutex
+	// blockedCh is closed once the gate is unblocked, it is nil if the gate is not blocked
+	blockedCh chan struct{}
+}
+
+func (g *gate) block() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.blockedCh == nil {
+		g.blockedCh = make(chan struct{})
+	}
+}
+
+func (g *gate) unblock() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.blockedCh != nil {
+		close(g.blockedCh)
+		g.blockedCh = nil
+	}
+}
+
+// wait waits until the gate is unblocked or the context is done
+func (g *gate) wait(ctx context.Context) error {
+	g.mu.Lock()
+	blockedCh := g.blockedCh
+	g.mu.Unlock()
+
+	if blockedCh == nil {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-blockedCh:
+		return nil
+	}
+}
@@ -6,4 +6,101 @@ type Config struct {
 	HeartbeatTimeout  time.Duration
 	ElectionTimeout   time.Duration
 	HeartbeatInterval time.Duration
+
+	// Join starts the server with an empty configuration instead of forming a cluster with the given peers,
+	// the server waits to be added to an existing cluster by AddServer.
+	Join bool
+
+	// NewPeer creates a peer for the server added by AddServer with the given address
+	NewPeer func(id uint32, address string) (Peer, error)
+
+	// LearnerMaxLag is the maximum number of logs that a learner can fall behind the leader to be promoted to a voter
+	LearnerMaxLag uint64
+
+	// PreVote makes a candidate check if it could win the election before increasing its term,
+	// so a server rejoining from a partition does not disrupt the cluster.
+	//
+	// Note that a leader stepping down by losing contact with a quorum of voters runs the pre-vote
+	// even if PreVote is false, until it hears from a leader again.
+	PreVote bool
+
+	// LeaseDriftMargin bounds the clock drift between servers, the leader lease is shorter than
+	// the smaller of HeartbeatTimeout and ElectionTimeout by the margin
+	LeaseDriftMargin time.Duration
+
+	// SessionTimeout is the time a client session can be inactive before the leader expires it,
+	// zero means sessions never expire
+	SessionTimeout time.Duration
+
+	// ForwardApplyCommand makes a non-leader server forward ApplyCommand to the leader and pass the response back,
+	// otherwise the server rejects the command with the hint of the leader
+	ForwardApplyCommand bool
+
+	// MaxInflightAppendEntries is the maximum number of AppendEntries with entries in flight to each peer,
+	// new entries are sent without waiting for responses until the window is full, default to 16
+	MaxInflightAppendEntries int
+
+	// MaxAppendEntries is the maximum number of entries in one AppendEntries, default to 64
+	MaxAppendEntries int
+
+	// MaxAppendEntriesSize is the maximum size in bytes of entries in one AppendEntries,
+	// an entry larger than it is sent alone, default to 1 MiB
+	MaxAppendEntriesSize int
+
+	// DisablePipeline makes the leader send AppendEntries by unary RPCs instead of streaming them
+	// by AppendEntriesPipeline, the leader also falls back to unary RPCs if a peer does not support the stream
+	DisablePipeline bool
+
+	// ReplicationTimeout is the deadline of each AppendEntries and InstallSnapshot sent by the leader,
+	// default to ElectionTimeout
+	ReplicationTimeout time.Duration
+}
+
+const (
+	defaultMaxInflightAppendEntries = 16
+	defaultMaxAppendEntries         = 64
+	defaultMaxAppendEntriesSize     = 1 << 20
+)
+
+func (c *Config) maxInflightAppendEntries() int {
+	if c.MaxInflightAppendEntries <= 0 {
+		return defaultMaxInflightAppendEntries
+	}
+
+	return c.MaxInflightAppendEntries
+}
+
+func (c *Config) maxAppendEntries() int {
+	if c.MaxAppendEntries <= 0 {
+		return defaultMaxAppendEntries
+	}
+
+	return c.MaxAppendEntries
+}
+
+func (c *Config) maxAppendEntriesSize() int {
+	if c.MaxAppendEntriesSize <= 0 {
+		return defaultMaxAppendEntriesSize
+	}
+
+	return c.MaxAppendEntriesSize
+}
+
+func (c *Config) replicationTimeout() time.Duration {
+	if c.ReplicationTimeout <= 0 {
+		return c.ElectionTimeout
+	}
+
+	return c.ReplicationTimeout
+}
+
+// leaseDuration is how long the leader lease lasts since a quorum of voters heard from the leader,
+// it must not outlast either timeout since followers may start an election once HeartbeatTimeout passes
+func (c *Config) leaseDuration() time.Duration {
+	timeout := c.HeartbeatTimeout
+	if c.ElectionTimeout < timeout {
+		timeout = c.ElectionTimeout
+	}
+
+	return timeout - c.LeaseDriftMargin
 }
@@ -0,0 +1,248 @@
+package raft
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/justin0u0/raft/pb"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	errConfigurationChanging = newRPCError(codes.Unavailable, "another configuration change is in progress")
+	errLeaderNotCommitted    = newRPCError(codes.Unavailable, "leader has not committed any log in its term")
+	errServerExists          = newRPCError(codes.AlreadyExists, "server already exists")
+	errServerNotFound        = newRPCError(codes.NotFound, "server not found")
+	errNotLearner            = newRPCError(codes.FailedPrecondition, "server is not a learner")
+	errLearnerNotCaughtUp    = newRPCError(codes.FailedPrecondition, "learner has not caught up with the leader")
+)
+
+// newConfiguration creates the initial configuration with the server itself and the given peers at their addresses
+func newConfiguration(id uint32, peers map[uint32]Peer) *pb.Configuration {
+	servers := []*pb.Server{{Id: id}}
+	for peerId, peer := range peers {
+		servers = append(servers, &pb.Server{Id: peerId, Address: peer.Address()})
+	}
+
+	sort.Slice(servers, func(i, j int) bool {
+		return servers[i].GetId() < servers[j].GetId()
+	})
+
+	return &pb.Configuration{Servers: servers}
+}
+
+// RPC handlers
+
+func (r *Raft) addServer(req *pb.AddServerRequest) (*pb.AddServerResponse, error) {
+	if err := r.checkConfigurationChange(); err != nil {
+		return nil, err
+	}
+
+	if r.inConfiguration(req.GetId()) {
+		return nil, errServerExists
+	}
+
+	servers := make([]*pb.Server, 0, len(r.configuration.GetServers())+1)
+	servers = append(servers, r.configuration.GetServers()...)
+	servers = append(servers, &pb.Server{Id: req.GetId(), Address: req.GetAddress(), Suffrage: req.GetSuffrage()})
+
+	e, err := r.appendConfiguration(&pb.Configuration{Servers: servers})
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.AddServerResponse{Entry: e}, nil
+}
+
+func (r *Raft) removeServer(req *pb.RemoveServerRequest) (*pb.RemoveServerResponse, error) {
+	if err := r.checkConfigurationChange(); err != nil {
+		return nil, err
+	}
+
+	if !r.inConfiguration(req.GetId()) {
+		return nil, errServerNotFound
+	}
+
+	servers := make([]*pb.Server, 0, len(r.configuration.GetServers()))
+	for _, server := range r.configuration.GetServers() {
+		if server.GetId() != req.GetId() {
+			servers = append(servers, server)
+		}
+	}
+
+	e, err := r.appendConfiguration(&pb.Configuration{Servers: servers})
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.RemoveServerResponse{Entry: e}, nil
+}
+
+func (r *Raft) promoteLearner(req *pb.PromoteLearnerRequest) (*pb.PromoteLearnerResponse, error) {
+	if err := r.checkConfigurationChange(); err != nil {
+		return nil, err
+	}
+
+	server := r.getServer(req.GetId())
+	if server == nil {
+		return nil, errServerNotFound
+	}
+
+	if server.GetSuffrage() != pb.Suffrage_LEARNER {
+		return nil, errNotLearner
+	}
+
+	lastLogId, _ := r.getLastLog()
+	if r.matchIndex[req.GetId()]+r.config.LearnerMaxLag < lastLogId {
+		return nil, errLearnerNotCaughtUp
+	}
+
+	servers := make([]*pb.Server, 0, len(r.configuration.GetServers()))
+	for _, server := range r.configuration.GetServers() {
+		if server.GetId() == req.GetId() {
+			server = &pb.Server{Id: server.GetId(), Address: server.GetAddress(), Suffrage: pb.Suffrage_VOTER}
+		}
+
+		servers = append(servers, server)
+	}
+
+	e, err := r.appendConfiguration(&pb.Configuration{Servers: servers})
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.PromoteLearnerResponse{Entry: e}, nil
+}
+
+// checkConfigurationChange checks if the leader can change the configuration,
+// only one server can be added or removed at a time
+func (r *Raft) checkConfigurationChange() error {
+	if r.state != Leader {
+		return r.notLeaderError()
+	}
+
+	if r.configurationId > r.commitIndex {
+		return errConfigurationChanging
+	}
+
+	// the previous configuration may be not committed by the previous leader
+	if term, _ := r.getLogTerm(r.commitIndex); term != r.currentTerm {
+		return errLeaderNotCommitted
+	}
+
+	return nil
+}
+
+// appendConfiguration appends the configuration log, the new configuration takes effect once it is appended
+func (r *Raft) appendConfiguration(configuration *pb.Configuration) (*pb.Entry, error) {
+	data, err := proto.Marshal(configuration)
+	if err != nil {
+		return nil, fmt.Errorf("fail to encode configuration: %w", err)
+	}
+
+	lastLogId, _ := r.getLastLog()
+	e := &pb.Entry{
+		Id:        lastLogId + 1,
+		Term:      r.currentTerm,
+		Type:      pb.EntryType_CONFIGURATION,
+		Data:      data,
+		Timestamp: time.Now().UnixNano(),
+	}
+	if err := r.appendLogs([]*pb.Entry{e}); err != nil {
+		return nil, fmt.Errorf("fail to append logs: %w", err)
+	}
+	r.notifyReplicate()
+
+	r.syncPeers()
+
+	r.logger.Info("append new configuration", zap.Uint64("configurationId", e.GetId()), zap.Any("servers", configuration.GetServers()))
+
+	return e, nil
+}
+
+// configuration related
+
+// syncPeers creates peers for servers in the latest configuration that are not known yet
+//
+// Note that peers of removed servers are kept so they can be reused if the server is added back.
+func (r *Raft) syncPeers() {
+	for _, server := range r.configuration.GetServers() {
+		peerId := server.GetId()
+		if _, ok := r.peers[peerId]; ok || peerId == r.id {
+			continue
+		}
+
+		if r.config.NewPeer == nil || server.GetAddress() == "" {
+			r.logger.Warn("unable to create peer for server in the configuration", zap.Uint32("peer", peerId))
+			continue
+		}
+
+		peer, err := r.config.NewPeer(peerId, server.GetAddress())
+		if err != nil {
+			r.logger.Error("fail to create peer", zap.Error(err), zap.Uint32("peer", peerId))
+			continue
+		}
+
+		r.mu.Lock()
+		r.peers[peerId] = peer
+		r.mu.Unlock()
+
+		if r.state == Leader {
+			lastLogId, _ := r.getLastLog()
+			r.resetProgress(peerId, lastLogId+1, 0)
+			r.lastContact[peerId] = time.Now()
+		}
+
+		r.logger.Info("create peer for new server", zap.Uint32("peer", peerId), zap.String("address", server.GetAddress()))
+	}
+}
+
+// getPeers gets peers of servers in the latest configuration, excluding the server itself
+func (r *Raft) getPeers() map[uint32]Peer {
+	peers := make(map[uint32]Peer)
+
+	for _, server := range r.configuration.GetServers() {
+		if peer, ok := r.peers[server.GetId()]; ok && server.GetId() != r.id {
+			peers[server.GetId()] = peer
+		}
+	}
+
+	return peers
+}
+
+// getServer gets the server in the latest configuration and returns nil if not found
+func (r *Raft) getServer(id uint32) *pb.Server {
+	for _, server := range r.configuration.GetServers() {
+		if server.GetId() == id {
+			return server
+		}
+	}
+
+	return nil
+}
+
+func (r *Raft) inConfiguration(id uint32) bool {
+	return r.getServer(id) != nil
+}
+
+// isVoter checks if the server is a voter in the latest configuration, learners are not voters
+func (r *Raft) isVoter(id uint32) bool {
+	server := r.getServer(id)
+
+	return server != nil && server.GetSuffrage() == pb.Suffrage_VOTER
+}
+
+// quorumSize returns the number of voters needed to win an election or commit a log
+func (r *Raft) quorumSize() int {
+	voters := 0
+	for _, server := range r.configuration.GetServers() {
+		if server.GetSuffrage() == pb.Suffrage_VOTER {
+			voters++
+		}
+	}
+
+	return voters/2 + 1
+}
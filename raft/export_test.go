@@ -0,0 +1,4 @@
+package raft
+
+// NewTestFilePersister exports newTestFilePersister to the conformance tests in package raft_test
+var NewTestFilePersister = newTestFilePersister
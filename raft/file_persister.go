@@ -0,0 +1,480 @@
+package raft
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/justin0u0/raft/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	stateFileName    = "state"
+	snapshotFileName = "snapshot"
+	walFileName      = "wal"
+
+	// a wal record is the length and the crc of the payload followed by the payload,
+	// the first byte of the payload is the record type
+	walRecordHeaderSize = 8
+
+	// walEntryRecord appends an entry, entries with the same or larger ids are deleted first
+	walEntryRecord byte = 1
+	// walTruncateRecord deletes all entries after the given id
+	walTruncateRecord byte = 2
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// FilePersister is a Persister that saves raft state in files under a directory:
+//
+//   - state: the current term and the vote, replaced by writing a temporary file, fsync and rename
+//   - snapshot: the snapshot and its metadata, replaced in the same way
+//   - wal: the write-ahead log of entries, records are appended and made durable by Sync
+//
+// Logs are also kept in memory for reads. A torn record at the end of the wal after a crash is truncated
+// when the persister is created, while a corrupted record followed by other records fails the creation.
+type FilePersister struct {
+	dir string
+	wal *os.File
+	// logs are logs in the wal
+	logs []*pb.Entry
+
+	mu sync.Mutex
+}
+
+var (
+	_ Persister = (*FilePersister)(nil)
+	_ Syncer    = (*FilePersister)(nil)
+)
+
+// NewFilePersister creates a FilePersister that saves raft state in the given directory,
+// logs in the existing wal are recovered
+func NewFilePersister(dir string) (*FilePersister, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("fail to create directory: %w", err)
+	}
+
+	wal, err := openWAL(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &FilePersister{dir: dir, wal: wal}
+	if err := p.recover(); err != nil {
+		wal.Close()
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Close closes the wal
+func (p *FilePersister) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.wal.Close()
+}
+
+func (p *FilePersister) FirstIndex() (uint64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return firstIndex(p.logs), nil
+}
+
+func (p *FilePersister) LastIndex() (uint64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return lastIndex(p.logs), nil
+}
+
+func (p *FilePersister) GetLog(id uint64) (*pb.Entry, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return getLog(p.logs, id)
+}
+
+func (p *FilePersister) StoreLogs(logs []*pb.Entry) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := checkLogs(p.logs, logs); err != nil {
+		return err
+	}
+
+	records, err := encodeEntryRecords(logs)
+	if err != nil {
+		return err
+	}
+
+	if _, err := p.wal.Write(records); err != nil {
+		return fmt.Errorf("fail to write wal: %w", err)
+	}
+
+	p.logs, _ = storeLogs(p.logs, logs)
+
+	return nil
+}
+
+func (p *FilePersister) DeleteRange(min, max uint64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	logs, err := deleteRange(p.logs, min, max)
+	if err != nil {
+		return err
+	}
+
+	if len(logs) == len(p.logs) {
+		return nil
+	}
+
+	if len(logs) != 0 && firstIndex(logs) == firstIndex(p.logs) {
+		// only logs at the end are deleted
+		body := make([]byte, 8)
+		binary.BigEndian.PutUint64(body, lastIndex(logs))
+
+		if _, err := p.wal.Write(encodeRecord(walTruncateRecord, body)); err != nil {
+			return fmt.Errorf("fail to write wal: %w", err)
+		}
+	} else if err := p.rewriteWAL(logs); err != nil {
+		return err
+	}
+
+	p.logs = logs
+
+	return nil
+}
+
+// Sync fsyncs the wal
+func (p *FilePersister) Sync() error {
+	p.mu.Lock()
+	wal := p.wal
+	p.mu.Unlock()
+
+	// fsync without holding the lock so logs can be stored at the same time
+	if err := wal.Sync(); err != nil && !errors.Is(err, os.ErrClosed) {
+		return fmt.Errorf("fail to sync wal: %w", err)
+	}
+
+	// the wal is closed after it is rewritten, and the new one is already fsynced
+	return nil
+}
+
+func (p *FilePersister) SetState(currentTerm uint64, votedFor uint32) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	data := make([]byte, 12)
+	binary.BigEndian.PutUint64(data[0:8], currentTerm)
+	binary.BigEndian.PutUint32(data[8:12], votedFor)
+
+	if err := writeFileAtomic(p.dir, stateFileName, data); err != nil {
+		return fmt.Errorf("fail to save state: %w", err)
+	}
+
+	return nil
+}
+
+func (p *FilePersister) GetState() (uint64, uint32, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	data, err := readFile(filepath.Join(p.dir, stateFileName))
+	if err != nil {
+		return 0, 0, fmt.Errorf("fail to read state: %w", err)
+	}
+
+	if data == nil {
+		return 0, 0, nil
+	}
+
+	if len(data) != 12 {
+		return 0, 0, errors.New("state file is corrupted")
+	}
+
+	return binary.BigEndian.Uint64(data[0:8]), binary.BigEndian.Uint32(data[8:12]), nil
+}
+
+func (p *FilePersister) SaveSnapshot(metadata *pb.SnapshotMetadata, snapshot []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	header, err := proto.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("fail to encode snapshot metadata: %w", err)
+	}
+
+	data := make([]byte, 4, 4+len(header)+len(snapshot))
+	binary.BigEndian.PutUint32(data, uint32(len(header)))
+	data = append(data, header...)
+	data = append(data, snapshot...)
+
+	if err := writeFileAtomic(p.dir, snapshotFileName, data); err != nil {
+		return fmt.Errorf("fail to save snapshot: %w", err)
+	}
+
+	return nil
+}
+
+func (p *FilePersister) LoadSnapshot() (*pb.SnapshotMetadata, []byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	data, err := readFile(filepath.Join(p.dir, snapshotFileName))
+	if err != nil {
+		return nil, nil, fmt.Errorf("fail to read snapshot: %w", err)
+	}
+
+	if data == nil {
+		return nil, nil, nil
+	}
+
+	if len(data) < 4 || uint64(len(data)-4) < uint64(binary.BigEndian.Uint32(data)) {
+		return nil, nil, errors.New("snapshot file is corrupted")
+	}
+
+	n := binary.BigEndian.Uint32(data)
+
+	metadata := &pb.SnapshotMetadata{}
+	if err := proto.Unmarshal(data[4:4+n], metadata); err != nil {
+		return nil, nil, fmt.Errorf("fail to decode snapshot metadata: %w", err)
+	}
+
+	return metadata, data[4+n:], nil
+}
+
+// recover reads logs in the wal and truncates the torn record at the end
+func (p *FilePersister) recover() error {
+	data, err := readFile(filepath.Join(p.dir, walFileName))
+	if err != nil {
+		return fmt.Errorf("fail to read wal: %w", err)
+	}
+
+	logs := []*pb.Entry{}
+
+	offset := 0
+	for offset < len(data) {
+		payload, ok := decodeRecord(data[offset:])
+		if !ok {
+			// truncating a bad record followed by others loses records that are already durable
+			if !isTornRecord(data[offset:]) {
+				return fmt.Errorf("wal record at offset %d is corrupted", offset)
+			}
+
+			break
+		}
+
+		logs, err = applyRecord(logs, payload)
+		if err != nil {
+			return fmt.Errorf("fail to apply wal record at offset %d: %w", offset, err)
+		}
+
+		offset += walRecordHeaderSize + len(payload)
+	}
+
+	if offset < len(data) {
+		if err := p.wal.Truncate(int64(offset)); err != nil {
+			return fmt.Errorf("fail to truncate torn wal record: %w", err)
+		}
+
+		if err := p.wal.Sync(); err != nil {
+			return fmt.Errorf("fail to sync wal: %w", err)
+		}
+	}
+
+	p.logs = logs
+
+	return nil
+}
+
+// rewriteWAL replaces the wal with the given logs
+func (p *FilePersister) rewriteWAL(logs []*pb.Entry) error {
+	records, err := encodeEntryRecords(logs)
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(p.dir, walFileName, records); err != nil {
+		return fmt.Errorf("fail to rewrite wal: %w", err)
+	}
+
+	wal, err := openWAL(p.dir)
+	if err != nil {
+		return err
+	}
+
+	p.wal.Close()
+	p.wal = wal
+
+	return nil
+}
+
+func openWAL(dir string) (*os.File, error) {
+	path := filepath.Join(dir, walFileName)
+
+	_, err := os.Stat(path)
+	created := errors.Is(err, os.ErrNotExist)
+
+	wal, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("fail to open wal: %w", err)
+	}
+
+	// fsync the directory so the new wal does not disappear after a crash with records synced to it
+	if created {
+		if err := syncDir(dir); err != nil {
+			wal.Close()
+			return nil, fmt.Errorf("fail to sync directory of wal: %w", err)
+		}
+	}
+
+	return wal, nil
+}
+
+func encodeEntryRecords(logs []*pb.Entry) ([]byte, error) {
+	records := []byte{}
+	for _, log := range logs {
+		body, err := proto.Marshal(log)
+		if err != nil {
+			return nil, fmt.Errorf("fail to encode entry: %w", err)
+		}
+
+		records = append(records, encodeRecord(walEntryRecord, body)...)
+	}
+
+	return records, nil
+}
+
+func encodeRecord(recordType byte, body []byte) []byte {
+	payload := make([]byte, 0, 1+len(body))
+	payload = append(payload, recordType)
+	payload = append(payload, body...)
+
+	record := make([]byte, walRecordHeaderSize, walRecordHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.Checksum(payload, crcTable))
+
+	return append(record, payload...)
+}
+
+// decodeRecord decodes the payload of the first record and returns false if the record is torn or corrupted
+func decodeRecord(data []byte) ([]byte, bool) {
+	if len(data) < walRecordHeaderSize {
+		return nil, false
+	}
+
+	n := binary.BigEndian.Uint32(data[0:4])
+	if n == 0 || uint64(len(data)-walRecordHeaderSize) < uint64(n) {
+		return nil, false
+	}
+
+	payload := data[walRecordHeaderSize : walRecordHeaderSize+int(n)]
+	if crc32.Checksum(payload, crcTable) != binary.BigEndian.Uint32(data[4:8]) {
+		return nil, false
+	}
+
+	return payload, true
+}
+
+// isTornRecord checks if the bad record at the start of data is the last record of the wal,
+// which is partially written if the server crashes while appending it, the rest may also be zero filled
+func isTornRecord(data []byte) bool {
+	if len(data) < walRecordHeaderSize {
+		return true
+	}
+
+	if n := binary.BigEndian.Uint32(data[0:4]); uint64(len(data)-walRecordHeaderSize) <= uint64(n) {
+		return true
+	}
+
+	for _, b := range data {
+		if b != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// applyRecord applies the record to logs read from the wal
+func applyRecord(logs []*pb.Entry, payload []byte) ([]*pb.Entry, error) {
+	switch payload[0] {
+	case walEntryRecord:
+		log := &pb.Entry{}
+		if err := proto.Unmarshal(payload[1:], log); err != nil {
+			return nil, err
+		}
+
+		return storeLogs(logs, []*pb.Entry{log})
+
+	case walTruncateRecord:
+		if len(payload) != 9 {
+			return nil, errors.New("invalid truncate record")
+		}
+
+		return truncateLogs(logs, binary.BigEndian.Uint64(payload[1:])), nil
+
+	default:
+		return nil, fmt.Errorf("unknown record type %d", payload[0])
+	}
+}
+
+// readFile reads the file and returns nil if it does not exist
+func readFile(name string) ([]byte, error) {
+	data, err := os.ReadFile(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	return data, err
+}
+
+// writeFileAtomic replaces the file by writing a temporary file, fsync and rename
+func writeFileAtomic(dir, name string, data []byte) error {
+	tmp := filepath.Join(dir, name+".tmp")
+
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, filepath.Join(dir, name)); err != nil {
+		return err
+	}
+
+	// fsync the directory so the rename is durable
+	return syncDir(dir)
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
@@ -0,0 +1,172 @@
+package raft
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/justin0u0/raft/pb"
+)
+
+func newTestFilePersister(t *testing.T, dir string) *FilePersister {
+	p, err := NewFilePersister(dir)
+	if err != nil {
+		t.Fatalf("fail to create file persister: %v", err)
+	}
+
+	t.Cleanup(func() { p.Close() })
+
+	return p
+}
+
+// checkPersistedLogs checks terms of all logs from the first index
+func checkPersistedLogs(t *testing.T, s LogStore, terms ...uint64) {
+	firstId, _ := s.FirstIndex()
+	lastId, _ := s.LastIndex()
+
+	if firstId != 1 || lastId != uint64(len(terms)) {
+		t.Fatalf("expect logs between 1 and %d, got logs between %d and %d", len(terms), firstId, lastId)
+	}
+
+	for i, term := range terms {
+		log, err := s.GetLog(uint64(i + 1))
+		if err != nil || log.GetTerm() != term {
+			t.Fatalf("expect log %d with term %d, got %v, %v", i+1, term, log, err)
+		}
+	}
+}
+
+func TestFilePersisterRecovery(t *testing.T) {
+	dir := t.TempDir()
+
+	p := newTestFilePersister(t, dir)
+	if err := p.SetState(1, 1); err != nil {
+		t.Fatalf("fail to save state: %v", err)
+	}
+	if err := p.StoreLogs([]*pb.Entry{{Id: 1, Term: 1}, {Id: 2, Term: 1}, {Id: 3, Term: 1}}); err != nil {
+		t.Fatalf("fail to store logs: %v", err)
+	}
+
+	// log 3 is overwritten by a new leader
+	if err := p.SetState(2, 2); err != nil {
+		t.Fatalf("fail to save state: %v", err)
+	}
+	if err := p.DeleteRange(3, 3); err != nil {
+		t.Fatalf("fail to delete logs: %v", err)
+	}
+	if err := p.StoreLogs([]*pb.Entry{{Id: 3, Term: 2}, {Id: 4, Term: 2}}); err != nil {
+		t.Fatalf("fail to store logs: %v", err)
+	}
+	p.Close()
+
+	p = newTestFilePersister(t, dir)
+	defer p.Close()
+
+	currentTerm, votedFor, err := p.GetState()
+	if err != nil || currentTerm != 2 || votedFor != 2 {
+		t.Fatalf("expect term 2 and vote 2, got term %d and vote %d, %v", currentTerm, votedFor, err)
+	}
+	checkPersistedLogs(t, p, 1, 1, 2, 2)
+}
+
+func TestFilePersisterTornRecord(t *testing.T) {
+	dir := t.TempDir()
+
+	p := newTestFilePersister(t, dir)
+	if err := p.StoreLogs([]*pb.Entry{{Id: 1, Term: 1, Data: []byte("a")}, {Id: 2, Term: 1, Data: []byte("b")}}); err != nil {
+		t.Fatalf("fail to store logs: %v", err)
+	}
+	p.Close()
+
+	// simulate a crash in the middle of appending log 3
+	name := filepath.Join(dir, walFileName)
+	info, err := os.Stat(name)
+	if err != nil {
+		t.Fatalf("fail to stat wal: %v", err)
+	}
+	size := info.Size()
+
+	record := encodeRecord(walEntryRecord, []byte{0x08, 0x03, 0x10, 0x01})
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatalf("fail to open wal: %v", err)
+	}
+	f.Write(record[:len(record)-2])
+	f.Close()
+
+	p = newTestFilePersister(t, dir)
+	defer p.Close()
+
+	checkPersistedLogs(t, p, 1, 1)
+
+	if info, err := os.Stat(name); err != nil || info.Size() != size {
+		t.Fatalf("expect the torn record is truncated")
+	}
+
+	// logs are appended after the truncated record
+	if err := p.StoreLogs([]*pb.Entry{{Id: 3, Term: 1, Data: []byte("c")}}); err != nil {
+		t.Fatalf("fail to store logs: %v", err)
+	}
+
+	reopened := newTestFilePersister(t, dir)
+	defer reopened.Close()
+
+	checkPersistedLogs(t, reopened, 1, 1, 1)
+}
+
+func TestFilePersisterCorruptedRecord(t *testing.T) {
+	dir := t.TempDir()
+
+	p := newTestFilePersister(t, dir)
+	if err := p.StoreLogs([]*pb.Entry{{Id: 1, Term: 1, Data: []byte("a")}, {Id: 2, Term: 1, Data: []byte("b")}}); err != nil {
+		t.Fatalf("fail to store logs: %v", err)
+	}
+	p.Close()
+
+	// flip a byte in the payload of log 1, which is followed by the record of log 2
+	name := filepath.Join(dir, walFileName)
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatalf("fail to read wal: %v", err)
+	}
+	data[walRecordHeaderSize+1] ^= 0xff
+	if err := os.WriteFile(name, data, 0o644); err != nil {
+		t.Fatalf("fail to write wal: %v", err)
+	}
+
+	if _, err := NewFilePersister(dir); err == nil {
+		t.Fatal("expect the corrupted record fails the recovery")
+	}
+
+	// durable records are not truncated
+	if info, err := os.Stat(name); err != nil || info.Size() != int64(len(data)) {
+		t.Fatalf("expect the wal is not truncated")
+	}
+}
+
+func TestFilePersisterDeletePrefix(t *testing.T) {
+	dir := t.TempDir()
+
+	p := newTestFilePersister(t, dir)
+	if err := p.StoreLogs([]*pb.Entry{{Id: 1, Term: 1}, {Id: 2, Term: 1}, {Id: 3, Term: 1}}); err != nil {
+		t.Fatalf("fail to store logs: %v", err)
+	}
+
+	// logs in the snapshot are deleted by rewriting the wal
+	if err := p.DeleteRange(1, 2); err != nil {
+		t.Fatalf("fail to delete logs: %v", err)
+	}
+	if err := p.StoreLogs([]*pb.Entry{{Id: 4, Term: 2}}); err != nil {
+		t.Fatalf("fail to store logs: %v", err)
+	}
+	p.Close()
+
+	p = newTestFilePersister(t, dir)
+	defer p.Close()
+
+	firstId, _ := p.FirstIndex()
+	lastId, _ := p.LastIndex()
+	if firstId != 3 || lastId != 4 {
+		t.Fatalf("expect logs between 3 and 4, got logs between %d and %d", firstId, lastId)
+	}
+}
@@ -0,0 +1,352 @@
+package raft
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/justin0u0/raft/pb"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+)
+
+// restoreRetryInterval is the interval to restore the FSM again after it fails to restore from a snapshot
+const restoreRetryInterval = 100 * time.Millisecond
+
+var (
+	errFSMSnapshotUnsupported = newRPCError(codes.FailedPrecondition, "FSM does not take snapshots, use Snapshot instead")
+	errFSMNotRestored         = newRPCError(codes.Unavailable, "FSM is not restored from the snapshot yet")
+)
+
+// FSM is the replicated state machine of the application, it is driven by the apply goroutine of raft,
+// so Apply, Snapshot and Restore are never called concurrently.
+type FSM interface {
+	// Apply applies a committed command, the result is responded to the client waiting on ApplyCommand,
+	// and it must be []byte, string or nil.
+	Apply(entry *pb.Entry) interface{}
+	// Snapshot encodes the state after all applied commands.
+	Snapshot() ([]byte, error)
+	// Restore replaces the state with the snapshot, it is called again later if it fails,
+	// and no logs are applied until it succeeds.
+	Restore(snapshot io.Reader) error
+}
+
+// channelFSM is the FSM used if NewRaft is given no FSM, it delivers committed commands to ApplyCh
+// and snapshots to SnapshotCh
+type channelFSM struct {
+	applyCh    chan *pb.Entry
+	snapshotCh chan *Snapshot
+	// doneCh is closed once the context of Run is done, so the apply goroutine stops delivering
+	// to an application that no longer consumes the channels
+	doneCh <-chan struct{}
+}
+
+func newChannelFSM() *channelFSM {
+	return &channelFSM{
+		applyCh:    make(chan *pb.Entry),
+		snapshotCh: make(chan *Snapshot),
+	}
+}
+
+// Apply delivers the command to ApplyCh, the application has no way to respond a result,
+// so commands are responded with an empty result
+func (f *channelFSM) Apply(entry *pb.Entry) interface{} {
+	select {
+	case <-f.doneCh:
+	case f.applyCh <- entry:
+	}
+
+	return nil
+}
+
+// Snapshot is not supported since the application passes snapshots to raft by itself
+func (f *channelFSM) Snapshot() ([]byte, error) {
+	return nil, errFSMSnapshotUnsupported
+}
+
+func (f *channelFSM) Restore(snapshot io.Reader) error {
+	data, err := io.ReadAll(snapshot)
+	if err != nil {
+		return err
+	}
+
+	s := &Snapshot{Data: data}
+	if r, ok := snapshot.(*snapshotReader); ok {
+		s.LastIncludedId, s.LastIncludedTerm = r.lastIncludedId, r.lastIncludedTerm
+	}
+
+	select {
+	case <-f.doneCh:
+		return errRaftStopped
+	case f.snapshotCh <- s:
+		return nil
+	}
+}
+
+// snapshotReader is the snapshot passed to FSM.Restore with the last log included in the snapshot
+type snapshotReader struct {
+	*bytes.Reader
+	lastIncludedId   uint64
+	lastIncludedTerm uint64
+}
+
+// encodeResult converts the result of FSM.Apply to the result responded to the client
+func encodeResult(result interface{}) ([]byte, error) {
+	switch result := result.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return result, nil
+	case string:
+		return []byte(result), nil
+	default:
+		return nil, fmt.Errorf("unsupported result type %T", result)
+	}
+}
+
+// fsmSnapshotRequest asks the apply goroutine to snapshot the FSM after all applied logs
+type fsmSnapshotRequest struct {
+	respCh chan *fsmSnapshotResponse
+}
+
+type fsmSnapshotResponse struct {
+	id   uint64
+	data []byte
+	err  error
+}
+
+// TakeSnapshot snapshots the FSM after all applied logs, and discards these logs,
+// it returns the last log id included in the snapshot.
+func (r *Raft) TakeSnapshot(ctx context.Context) (uint64, error) {
+	req := &fsmSnapshotRequest{respCh: make(chan *fsmSnapshotResponse, 1)}
+
+	select {
+	case <-ctx.Done():
+		return 0, errRPCTimeout
+	case r.fsmSnapshotCh <- req:
+	}
+
+	var resp *fsmSnapshotResponse
+	select {
+	case <-ctx.Done():
+		return 0, errRPCTimeout
+	case resp = <-req.respCh:
+	}
+
+	if resp.err != nil {
+		return 0, fmt.Errorf("fail to snapshot FSM: %w", resp.err)
+	}
+
+	if err := r.Snapshot(ctx, resp.id, resp.data); err != nil {
+		return 0, err
+	}
+
+	return resp.id, nil
+}
+
+// notifyApply notifies the apply goroutine that logs are committed or a snapshot is installed
+func (r *Raft) notifyApply() {
+	select {
+	case r.applyNotifyCh <- struct{}{}:
+	default:
+	}
+}
+
+// runApply applies committed logs to the FSM until the context is done,
+// the FSM is called without holding the lock so a slow application does not block the state loop
+func (r *Raft) runApply(ctx context.Context) {
+	if f, ok := r.fsm.(*channelFSM); ok {
+		f.doneCh = ctx.Done()
+	}
+
+	// retryCh is set if the FSM fails to restore from the snapshot, no logs are applied until it is restored
+	var retryCh <-chan time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-r.applyNotifyCh:
+			if retryCh == nil {
+				retryCh = r.retryApplyLogs(ctx)
+			}
+
+		case <-retryCh:
+			retryCh = r.retryApplyLogs(ctx)
+
+		case req := <-r.fsmSnapshotCh:
+			// the state of the FSM is unknown if it fails to restore from the snapshot
+			if r.hasPendingSnapshot() {
+				req.respCh <- &fsmSnapshotResponse{err: errFSMNotRestored}
+				continue
+			}
+
+			id := r.getLastApplied()
+			data, err := r.fsm.Snapshot()
+			req.respCh <- &fsmSnapshotResponse{id: id, data: data, err: err}
+		}
+	}
+}
+
+// retryApplyLogs applies logs, and returns the channel to retry if the FSM fails to restore from the snapshot
+func (r *Raft) retryApplyLogs(ctx context.Context) <-chan time.Time {
+	if err := r.applyLogs(ctx); err != nil && ctx.Err() == nil {
+		r.logger.Error("fail to restore FSM from snapshot, retry later", zap.Error(err))
+
+		return time.After(restoreRetryInterval)
+	}
+
+	return nil
+}
+
+func (r *Raft) hasPendingSnapshot() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.pendingSnapshot != nil
+}
+
+// applyLogs restores the pending snapshot and applies logs between (lastApplied, commitIndex],
+// the snapshot is kept pending if the FSM fails to restore from it
+func (r *Raft) applyLogs(ctx context.Context) error {
+	for {
+		r.mu.Lock()
+		snapshot := r.pendingSnapshot
+		r.pendingSnapshot = nil
+
+		var logs []*pb.Entry
+		if snapshot == nil {
+			logs = r.getLogsBetween(r.lastApplied+1, r.commitIndex)
+		}
+		r.mu.Unlock()
+
+		if snapshot != nil {
+			if err := r.restoreFSM(snapshot); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if len(logs) == 0 {
+			return nil
+		}
+
+		for _, log := range logs {
+			if err := r.applyLog(ctx, log); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// applyLog applies the log to the FSM, `lastApplied` is not moved if the context is done while the log is applied
+func (r *Raft) applyLog(ctx context.Context, log *pb.Entry) error {
+	r.mu.Lock()
+	future, ok := r.futures[log.GetId()]
+	if ok {
+		delete(r.futures, log.GetId())
+
+		if future.term != log.GetTerm() {
+			future.fail(errEntryOverwritten)
+			future = nil
+		}
+	}
+	session, duplicate := r.sessions[log.GetClientId()], log.GetClientId() != 0 && isDuplicate(r.sessions, log)
+	expired := log.GetClientId() != 0 && isExpired(r.sessions, log)
+	r.mu.Unlock()
+
+	var result []byte
+	// futureResult and futureErr resolve the future once the log is applied,
+	// so a client retrying after the response finds the updated session
+	var futureResult []byte
+	var futureErr error
+
+	switch {
+	case log.GetType() != pb.EntryType_COMMAND:
+		// logs that are internal to raft are not delivered to the application
+
+	case duplicate:
+		// the command is not applied again, and the future is resolved with the cached result
+		if session.GetSequence() == log.GetSequence() {
+			futureResult = session.GetResult()
+		} else {
+			futureErr = errSequenceOutdated
+		}
+
+	case expired:
+		// the command is not applied, the client must open a new session
+		futureErr = errSessionExpired
+
+	default:
+		var err error
+		if result, err = encodeResult(r.fsm.Apply(log)); err != nil {
+			r.logger.Error("fail to encode result of the applied log", zap.Error(err), zap.Uint64("id", log.GetId()))
+		}
+
+		futureResult = result
+	}
+
+	// the log may not be delivered to ApplyCh if the context is done
+	if err := ctx.Err(); err != nil {
+		if future != nil {
+			future.fail(errRaftStopped)
+		}
+
+		return err
+	}
+
+	r.mu.Lock()
+	if log.GetClientId() != 0 && !duplicate && !expired {
+		r.sessionResults[log.GetId()] = result
+	}
+	updateSessions(r.sessions, log, result)
+
+	r.lastApplied = log.GetId()
+	r.notifyApplied()
+	r.mu.Unlock()
+
+	if future == nil {
+		return nil
+	}
+
+	if futureErr != nil {
+		future.fail(futureErr)
+	} else {
+		future.respCh <- futureResult
+	}
+
+	return nil
+}
+
+// restoreFSM restores the FSM from the snapshot, and moves `lastApplied` to the snapshot,
+// the snapshot is pending again if the FSM fails to restore from it and no newer snapshot is installed
+func (r *Raft) restoreFSM(snapshot *Snapshot) error {
+	err := r.fsm.Restore(&snapshotReader{
+		Reader:           bytes.NewReader(snapshot.Data),
+		lastIncludedId:   snapshot.LastIncludedId,
+		lastIncludedTerm: snapshot.LastIncludedTerm,
+	})
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err != nil {
+		if r.pendingSnapshot == nil {
+			r.pendingSnapshot = snapshot
+		}
+
+		return err
+	}
+
+	r.lastApplied = snapshot.LastIncludedId
+	r.sessions = newSessions(r.lastIncludedSessions)
+	r.sessionResults = make(map[uint64][]byte)
+
+	r.notifyApplied()
+
+	return nil
+}
@@ -0,0 +1,325 @@
+package raft
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"io"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/justin0u0/raft/pb"
+	"go.uber.org/zap"
+)
+
+// testFSM records data of applied commands, Apply blocks while blockCh is not closed,
+// and Restore fails while failRestores is positive
+type testFSM struct {
+	mu           sync.Mutex
+	data         []string
+	restores     int
+	failRestores int
+	blockCh      chan struct{}
+}
+
+func newTestFSM() *testFSM {
+	blockCh := make(chan struct{})
+	close(blockCh)
+
+	return &testFSM{blockCh: blockCh}
+}
+
+func (f *testFSM) Apply(entry *pb.Entry) interface{} {
+	<-f.blockCh
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.data = append(f.data, string(entry.GetData()))
+
+	// respond the number of applied commands as the result
+	return strconv.Itoa(len(f.data))
+}
+
+func (f *testFSM) Snapshot() ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	buf := bytes.Buffer{}
+	if err := gob.NewEncoder(&buf).Encode(f.data); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (f *testFSM) Restore(snapshot io.Reader) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.restores++
+	f.data = nil
+
+	if f.failRestores > 0 {
+		f.failRestores--
+		return errors.New("fail to restore")
+	}
+
+	return gob.NewDecoder(snapshot).Decode(&f.data)
+}
+
+func (f *testFSM) getData() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]string{}, f.data...)
+}
+
+func startSingleNode(persister Persister, fsm FSM) (*Raft, context.CancelFunc) {
+	config := &Config{
+		HeartbeatTimeout:  150 * time.Millisecond,
+		ElectionTimeout:   150 * time.Millisecond,
+		HeartbeatInterval: 50 * time.Millisecond,
+	}
+
+	r := NewRaft(1, map[uint32]Peer{}, persister, fsm, config, zap.NewNop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go r.Run(ctx)
+
+	// wait for the server to elect itself
+	time.Sleep(1 * time.Second)
+
+	return r, cancel
+}
+
+func TestFSMApplyAndRestore(t *testing.T) {
+	persister := NewMemoryPersister()
+
+	fsm := newTestFSM()
+	r, cancel := startSingleNode(persister, fsm)
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelCtx()
+
+	numLogs := 10
+	for i := 1; i <= numLogs; i++ {
+		resp, err := r.ApplyCommand(ctx, &pb.ApplyCommandRequest{Data: []byte("command " + strconv.Itoa(i)), Wait: true})
+		if err != nil {
+			t.Fatal("fail to apply command:", err)
+		}
+
+		if result := string(resp.GetResult()); result != strconv.Itoa(i) {
+			t.Fatalf("result should be responded by the FSM, got %q", result)
+		}
+	}
+
+	id, err := r.TakeSnapshot(ctx)
+	if err != nil {
+		t.Fatal("fail to take snapshot:", err)
+	}
+
+	r.mu.Lock()
+	lastIncludedId := r.lastIncludedId
+	r.mu.Unlock()
+
+	if lastIncludedId != id {
+		t.Fatalf("logs up to and including %d should be compacted, got %d", id, lastIncludedId)
+	}
+
+	// logs after the snapshot are applied after the FSM is restored
+	for i := numLogs + 1; i <= 2*numLogs; i++ {
+		if _, err := r.ApplyCommand(ctx, &pb.ApplyCommandRequest{Data: []byte("command " + strconv.Itoa(i)), Wait: true}); err != nil {
+			t.Fatal("fail to apply command:", err)
+		}
+	}
+	cancel()
+
+	restarted := newTestFSM()
+	r, cancel = startSingleNode(persister, restarted)
+	defer cancel()
+
+	if _, err := r.ApplyCommand(ctx, &pb.ApplyCommandRequest{Data: []byte("command " + strconv.Itoa(2*numLogs+1)), Wait: true}); err != nil {
+		t.Fatal("fail to apply command:", err)
+	}
+
+	restarted.mu.Lock()
+	restores := restarted.restores
+	restarted.mu.Unlock()
+
+	if restores != 1 {
+		t.Fatalf("FSM should be restored from the snapshot once, got %d", restores)
+	}
+
+	data := restarted.getData()
+	if len(data) != 2*numLogs+1 {
+		t.Fatalf("FSM should apply %d commands, got %d", 2*numLogs+1, len(data))
+	}
+	for i, d := range data {
+		if d != "command "+strconv.Itoa(i+1) {
+			t.Fatalf("command %d applied to the FSM is %q", i+1, d)
+		}
+	}
+}
+
+func TestFSMRestoreRetry(t *testing.T) {
+	persister := NewMemoryPersister()
+
+	r, cancel := startSingleNode(persister, newTestFSM())
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelCtx()
+
+	numLogs := 10
+	for i := 1; i <= numLogs; i++ {
+		if _, err := r.ApplyCommand(ctx, &pb.ApplyCommandRequest{Data: []byte("command " + strconv.Itoa(i)), Wait: true}); err != nil {
+			t.Fatal("fail to apply command:", err)
+		}
+	}
+
+	if _, err := r.TakeSnapshot(ctx); err != nil {
+		t.Fatal("fail to take snapshot:", err)
+	}
+	cancel()
+
+	// logs after the snapshot are not applied until the FSM is restored
+	restarted := newTestFSM()
+	restarted.failRestores = 2
+	r, cancel = startSingleNode(persister, restarted)
+	defer cancel()
+
+	if _, err := r.ApplyCommand(ctx, &pb.ApplyCommandRequest{Data: []byte("command " + strconv.Itoa(numLogs+1)), Wait: true}); err != nil {
+		t.Fatal("fail to apply command:", err)
+	}
+
+	restarted.mu.Lock()
+	restores := restarted.restores
+	restarted.mu.Unlock()
+
+	if restores != 3 {
+		t.Fatalf("FSM should be restored from the snapshot 3 times, got %d", restores)
+	}
+
+	data := restarted.getData()
+	if len(data) != numLogs+1 {
+		t.Fatalf("FSM should apply %d commands, got %d", numLogs+1, len(data))
+	}
+	for i, d := range data {
+		if d != "command "+strconv.Itoa(i+1) {
+			t.Fatalf("command %d applied to the FSM is %q", i+1, d)
+		}
+	}
+}
+
+func TestFSMApplyDoesNotBlockRaft(t *testing.T) {
+	fsm := newTestFSM()
+	fsm.blockCh = make(chan struct{})
+
+	r, cancel := startSingleNode(NewMemoryPersister(), fsm)
+	defer cancel()
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancelCtx()
+
+	// commands are appended and committed while the FSM is blocked
+	numLogs := 5
+	var lastLogId uint64
+	for i := 1; i <= numLogs; i++ {
+		resp, err := r.ApplyCommand(ctx, &pb.ApplyCommandRequest{Data: []byte("command " + strconv.Itoa(i))})
+		if err != nil {
+			t.Fatal("fail to apply command:", err)
+		}
+
+		lastLogId = resp.GetEntry().GetId()
+	}
+
+	// logs are committed even though none of them is applied
+	var commitIndex uint64
+	for deadline := time.Now().Add(500 * time.Millisecond); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		r.mu.Lock()
+		commitIndex = r.commitIndex
+		r.mu.Unlock()
+
+		if commitIndex >= lastLogId {
+			break
+		}
+	}
+
+	if commitIndex < lastLogId {
+		t.Fatalf("logs up to and including %d should be committed while the FSM is blocked, got %d", lastLogId, commitIndex)
+	}
+	if data := fsm.getData(); len(data) != 0 {
+		t.Fatalf("FSM should apply no commands while it is blocked, got %d", len(data))
+	}
+
+	close(fsm.blockCh)
+
+	if err := r.waitApplied(ctx, lastLogId); err != nil {
+		t.Fatal("fail to wait for logs to be applied:", err)
+	}
+
+	if data := fsm.getData(); len(data) != numLogs {
+		t.Fatalf("FSM should apply %d commands, got %d", numLogs, len(data))
+	}
+}
+
+func TestChannelFSMStopsWithRun(t *testing.T) {
+	r, cancel := startSingleNode(NewMemoryPersister(), nil)
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancelCtx()
+
+	// the command is committed but ApplyCh is not consumed
+	resp, err := r.ApplyCommand(ctx, &pb.ApplyCommandRequest{Data: []byte("command 1")})
+	if err != nil {
+		t.Fatal("fail to apply command:", err)
+	}
+	id := resp.GetEntry().GetId()
+
+	cancel()
+	time.Sleep(100 * time.Millisecond)
+
+	select {
+	case e := <-r.ApplyCh():
+		t.Fatalf("log %d should not be delivered to ApplyCh after raft stops", e.GetId())
+	default:
+	}
+
+	if lastApplied := r.getLastApplied(); lastApplied >= id {
+		t.Fatalf("log %d should not be applied after raft stops, last applied log is %d", id, lastApplied)
+	}
+}
+
+func TestFSMClientSessionResult(t *testing.T) {
+	fsm := newTestFSM()
+	r, cancel := startSingleNode(NewMemoryPersister(), fsm)
+	defer cancel()
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancelCtx()
+
+	req := &pb.ApplyCommandRequest{Data: []byte("command 1"), Wait: true, ClientId: 1, Sequence: 1}
+	if _, err := r.ApplyCommand(ctx, req); err != nil {
+		t.Fatal("fail to apply command:", err)
+	}
+
+	if _, err := r.ApplyCommand(ctx, &pb.ApplyCommandRequest{Data: []byte("command 2"), Wait: true}); err != nil {
+		t.Fatal("fail to apply command:", err)
+	}
+
+	// the retried command is responded with the result cached when it was applied by the FSM
+	resp, err := r.ApplyCommand(ctx, req)
+	if err != nil {
+		t.Fatal("fail to retry command:", err)
+	}
+	if result := string(resp.GetResult()); result != "1" {
+		t.Fatalf("retried command should be responded with the cached result %q, got %q", "1", result)
+	}
+
+	if data := fsm.getData(); len(data) != 2 {
+		t.Fatalf("FSM should apply 2 commands, got %d", len(data))
+	}
+}
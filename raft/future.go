@@ -0,0 +1,48 @@
+package raft
+
+import (
+	"context"
+
+	"github.com/justin0u0/raft/pb"
+	"google.golang.org/grpc/codes"
+)
+
+var (
+	errLeadershipLost   = newRPCError(codes.Unavailable, "leadership lost before the entry is committed")
+	errEntryOverwritten = newRPCError(codes.Aborted, "entry is overwritten by another leader")
+)
+
+// applyFuture is the result of a command waiting to be committed and applied
+type applyFuture struct {
+	term   uint64
+	respCh chan []byte
+	errCh  chan error
+}
+
+func newApplyFuture() *applyFuture {
+	return &applyFuture{
+		respCh: make(chan []byte, 1),
+		errCh:  make(chan error, 1),
+	}
+}
+
+func (f *applyFuture) fail(err error) {
+	f.errCh <- err
+}
+
+func (f *applyFuture) wait(ctx context.Context) ([]byte, error) {
+	select {
+	case <-ctx.Done():
+		return nil, errRPCTimeout
+	case err := <-f.errCh:
+		return nil, err
+	case result := <-f.respCh:
+		return result, nil
+	}
+}
+
+// applyCommandRequest is the ApplyCommand request with the future to be resolved once the entry is applied
+type applyCommandRequest struct {
+	*pb.ApplyCommandRequest
+	future *applyFuture
+}
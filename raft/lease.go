@@ -0,0 +1,112 @@
+package raft
+
+import (
+	"sort"
+	"time"
+
+	"github.com/justin0u0/raft/pb"
+	"go.uber.org/zap"
+)
+
+// LeaseState is the state of the leader lease
+type LeaseState struct {
+	// Expiry is the time the lease expires, it is zero if the server is not the leader
+	Expiry time.Time
+	// LeaseReads is the number of lease reads served from the lease
+	LeaseReads uint64
+	// FallbackReads is the number of lease reads that confirm the leadership with a heartbeat round
+	// since the lease is expired
+	FallbackReads uint64
+}
+
+// Valid checks if reads can be served from the lease
+func (s LeaseState) Valid() bool {
+	return time.Now().Before(s.Expiry)
+}
+
+// LeaseState returns the state of the leader lease
+func (r *Raft) LeaseState() LeaseState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.leaseState
+}
+
+// RPC handlers
+
+// leaseRead serves the read from the lease if it is valid, otherwise confirms the leadership with a heartbeat round
+func (r *Raft) leaseRead(rpc *rpc, req *pb.LeaseReadRequest) {
+	if err := r.checkRead(); err != nil {
+		rpc.respond(nil, err)
+		return
+	}
+
+	r.mu.Lock()
+	valid := r.leaseState.Valid()
+	if valid {
+		r.leaseState.LeaseReads++
+	} else {
+		r.leaseState.FallbackReads++
+	}
+	r.mu.Unlock()
+
+	if !valid {
+		r.logger.Debug("lease expired, confirm leadership with a heartbeat round")
+		r.addPendingRead(rpc, true)
+
+		return
+	}
+
+	r.logger.Debug("serve read from lease", zap.Uint64("readIndex", r.commitIndex))
+
+	rpc.respond(&pb.LeaseReadResponse{ReadIndex: r.commitIndex, Lease: true}, nil)
+}
+
+// leader related
+
+// extendLease extends the lease from the time the heartbeats are sent to a quorum of voters,
+// followers do not elect a new leader within the lease duration since then
+func (r *Raft) extendLease() {
+	// the lease must not be held while transferring the leadership since the target starts an election immediately
+	if r.leadershipTransfer != nil {
+		return
+	}
+
+	acks := make([]time.Time, 0, len(r.configuration.GetServers()))
+	for _, server := range r.configuration.GetServers() {
+		if server.GetSuffrage() != pb.Suffrage_VOTER {
+			continue
+		}
+
+		if server.GetId() == r.id {
+			acks = append(acks, time.Now())
+		} else {
+			acks = append(acks, r.lastAck[server.GetId()])
+		}
+	}
+
+	quorumSize := r.quorumSize()
+	if len(acks) < quorumSize {
+		return
+	}
+
+	sort.Slice(acks, func(i, j int) bool {
+		return acks[i].After(acks[j])
+	})
+
+	expiry := acks[quorumSize-1].Add(r.config.leaseDuration())
+
+	r.mu.Lock()
+	if expiry.After(r.leaseState.Expiry) {
+		r.leaseState.Expiry = expiry
+	}
+	r.mu.Unlock()
+}
+
+func (r *Raft) revokeLease() {
+	r.mu.Lock()
+	r.leaseState.Expiry = time.Time{}
+	r.mu.Unlock()
+
+	r.logger.Debug("revoke leader lease")
+}
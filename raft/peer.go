@@ -11,6 +11,9 @@ import (
 // Peer provides an interface to allow Raft to commnuncate with other nodes
 type Peer interface {
 	pb.RaftClient
+
+	// Address returns the address of the node, it is the address of the server in the initial configuration
+	Address() string
 }
 
 // peer is the implementation of Peer for testing.
@@ -19,6 +22,7 @@ type Peer interface {
 type peer struct {
 	pb.RaftClient
 
+	addr string
 	conn *grpc.ClientConn
 	mu   sync.Mutex
 }
@@ -32,6 +36,48 @@ func (p *peer) ApplyCommand(ctx context.Context, in *pb.ApplyCommandRequest, opt
 	return p.RaftClient.ApplyCommand(ctx, in, opts...)
 }
 
+func (p *peer) ReadIndex(ctx context.Context, in *pb.ReadIndexRequest, opts ...grpc.CallOption) (*pb.ReadIndexResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.RaftClient.ReadIndex(ctx, in, opts...)
+}
+
+func (p *peer) LeaseRead(ctx context.Context, in *pb.LeaseReadRequest, opts ...grpc.CallOption) (*pb.LeaseReadResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.RaftClient.LeaseRead(ctx, in, opts...)
+}
+
+func (p *peer) AddServer(ctx context.Context, in *pb.AddServerRequest, opts ...grpc.CallOption) (*pb.AddServerResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.RaftClient.AddServer(ctx, in, opts...)
+}
+
+func (p *peer) RemoveServer(ctx context.Context, in *pb.RemoveServerRequest, opts ...grpc.CallOption) (*pb.RemoveServerResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.RaftClient.RemoveServer(ctx, in, opts...)
+}
+
+func (p *peer) PromoteLearner(ctx context.Context, in *pb.PromoteLearnerRequest, opts ...grpc.CallOption) (*pb.PromoteLearnerResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.RaftClient.PromoteLearner(ctx, in, opts...)
+}
+
+func (p *peer) TransferLeadership(ctx context.Context, in *pb.TransferLeadershipRequest, opts ...grpc.CallOption) (*pb.TransferLeadershipResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.RaftClient.TransferLeadership(ctx, in, opts...)
+}
+
 func (p *peer) AppendEntries(ctx context.Context, in *pb.AppendEntriesRequest, opts ...grpc.CallOption) (*pb.AppendEntriesResponse, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -39,6 +85,14 @@ func (p *peer) AppendEntries(ctx context.Context, in *pb.AppendEntriesRequest, o
 	return p.RaftClient.AppendEntries(ctx, in, opts...)
 }
 
+// AppendEntriesPipeline only locks while opening the stream, requests on the stream are not serialized
+func (p *peer) AppendEntriesPipeline(ctx context.Context, opts ...grpc.CallOption) (pb.Raft_AppendEntriesPipelineClient, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.RaftClient.AppendEntriesPipeline(ctx, opts...)
+}
+
 func (p *peer) RequestVote(ctx context.Context, in *pb.RequestVoteRequest, opts ...grpc.CallOption) (*pb.RequestVoteResponse, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -46,6 +100,27 @@ func (p *peer) RequestVote(ctx context.Context, in *pb.RequestVoteRequest, opts
 	return p.RaftClient.RequestVote(ctx, in, opts...)
 }
 
+func (p *peer) InstallSnapshot(ctx context.Context, in *pb.InstallSnapshotRequest, opts ...grpc.CallOption) (*pb.InstallSnapshotResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.RaftClient.InstallSnapshot(ctx, in, opts...)
+}
+
+func (p *peer) TimeoutNow(ctx context.Context, in *pb.TimeoutNowRequest, opts ...grpc.CallOption) (*pb.TimeoutNowResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.RaftClient.TimeoutNow(ctx, in, opts...)
+}
+
+func (p *peer) Address() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.addr
+}
+
 func (p *peer) dial(addr string, opts ...grpc.DialOption) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -55,6 +130,7 @@ func (p *peer) dial(addr string, opts ...grpc.DialOption) error {
 		return err
 	}
 
+	p.addr = addr
 	p.conn = conn
 	p.RaftClient = pb.NewRaftClient(conn)
 
@@ -1,38 +1,243 @@
 package raft
 
 import (
+	"errors"
+	"fmt"
 	"sync"
+
+	"github.com/justin0u0/raft/pb"
 )
 
+// ErrLogNotFound is returned by LogStore.GetLog if the log does not exist
+var ErrLogNotFound = errors.New("log not found")
+
+// LogStore stores logs with consecutive ids
+type LogStore interface {
+	// FirstIndex returns the first log id, or zero if there are no logs
+	FirstIndex() (uint64, error)
+	// LastIndex returns the last log id, or zero if there are no logs
+	LastIndex() (uint64, error)
+	// GetLog gets the log by the given log id and returns ErrLogNotFound if not found
+	GetLog(id uint64) (*pb.Entry, error)
+	// StoreLogs stores logs with consecutive ids, logs with the same or larger ids are replaced,
+	// the first log must directly follow the last log unless there are no logs
+	StoreLogs(logs []*pb.Entry) error
+	// DeleteRange deletes logs between min and max inclusive, the range must include the first or the last log
+	DeleteRange(min, max uint64) error
+}
+
+// Syncer is implemented by a LogStore whose writes are durable only after Sync,
+// so raft can make concurrent writes durable with one fsync
+type Syncer interface {
+	// Sync makes all previous writes durable
+	Sync() error
+}
+
+// StableStore stores the current term and the vote
+type StableStore interface {
+	// SetState saves the current term and the vote atomically
+	SetState(currentTerm uint64, votedFor uint32) error
+	// GetState returns zero-values if not saved
+	GetState() (currentTerm uint64, votedFor uint32, err error)
+}
+
+// SnapshotStore stores the latest snapshot
+type SnapshotStore interface {
+	// SaveSnapshot replaces the snapshot and its metadata atomically
+	SaveSnapshot(metadata *pb.SnapshotMetadata, snapshot []byte) error
+	// LoadSnapshot returns nil metadata if not saved
+	LoadSnapshot() (*pb.SnapshotMetadata, []byte, error)
+}
+
+// Persister is the storage of raft, logs are written to the log store when they are appended or deleted,
+// so only changes are written
 type Persister interface {
-	SaveRaftState(raftState []byte) error
-	LoadRaftState() ([]byte, error)
+	LogStore
+	StableStore
+	SnapshotStore
 }
 
-type persister struct {
-	raftState []byte
-	mu        sync.Mutex
+// MemoryPersister is a Persister that keeps everything in memory
+type MemoryPersister struct {
+	currentTerm uint64
+	votedFor    uint32
+	logs        []*pb.Entry
+	metadata    *pb.SnapshotMetadata
+	snapshot    []byte
+	mu          sync.Mutex
 }
 
-var _ Persister = (*persister)(nil)
+var _ Persister = (*MemoryPersister)(nil)
 
-func newPersister() *persister {
-	return &persister{}
+func NewMemoryPersister() *MemoryPersister {
+	return &MemoryPersister{}
 }
 
-func (p *persister) SaveRaftState(raftState []byte) error {
+func (p *MemoryPersister) FirstIndex() (uint64, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	p.raftState = make([]byte, len(raftState))
-	copy(p.raftState, raftState)
+	return firstIndex(p.logs), nil
+}
+
+func (p *MemoryPersister) LastIndex() (uint64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return lastIndex(p.logs), nil
+}
+
+func (p *MemoryPersister) GetLog(id uint64) (*pb.Entry, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return getLog(p.logs, id)
+}
+
+func (p *MemoryPersister) StoreLogs(logs []*pb.Entry) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stored, err := storeLogs(p.logs, logs)
+	if err != nil {
+		return err
+	}
+
+	p.logs = stored
 
 	return nil
 }
 
-func (p *persister) LoadRaftState() ([]byte, error) {
+func (p *MemoryPersister) DeleteRange(min, max uint64) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	return p.raftState, nil
+	logs, err := deleteRange(p.logs, min, max)
+	if err != nil {
+		return err
+	}
+
+	p.logs = logs
+
+	return nil
+}
+
+func (p *MemoryPersister) SetState(currentTerm uint64, votedFor uint32) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.currentTerm = currentTerm
+	p.votedFor = votedFor
+
+	return nil
+}
+
+func (p *MemoryPersister) GetState() (uint64, uint32, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.currentTerm, p.votedFor, nil
+}
+
+func (p *MemoryPersister) SaveSnapshot(metadata *pb.SnapshotMetadata, snapshot []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.metadata = metadata
+	p.snapshot = make([]byte, len(snapshot))
+	copy(p.snapshot, snapshot)
+
+	return nil
+}
+
+func (p *MemoryPersister) LoadSnapshot() (*pb.SnapshotMetadata, []byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.metadata, p.snapshot, nil
+}
+
+// helpers of logs with consecutive ids kept in a slice
+
+func firstIndex(logs []*pb.Entry) uint64 {
+	if len(logs) == 0 {
+		return 0
+	}
+
+	return logs[0].GetId()
+}
+
+func lastIndex(logs []*pb.Entry) uint64 {
+	if len(logs) == 0 {
+		return 0
+	}
+
+	return logs[len(logs)-1].GetId()
+}
+
+func getLog(logs []*pb.Entry, id uint64) (*pb.Entry, error) {
+	if len(logs) == 0 || id < logs[0].GetId() || id > lastIndex(logs) {
+		return nil, ErrLogNotFound
+	}
+
+	return logs[id-logs[0].GetId()], nil
+}
+
+// storeLogs returns logs after storing the new logs
+func storeLogs(logs []*pb.Entry, newLogs []*pb.Entry) ([]*pb.Entry, error) {
+	if err := checkLogs(logs, newLogs); err != nil {
+		return nil, err
+	}
+
+	if len(newLogs) == 0 {
+		return logs, nil
+	}
+
+	return append(truncateLogs(logs, newLogs[0].GetId()-1), newLogs...), nil
+}
+
+// checkLogs checks if the new logs have consecutive ids and can be stored after logs
+func checkLogs(logs []*pb.Entry, newLogs []*pb.Entry) error {
+	for i := 1; i < len(newLogs); i++ {
+		if newLogs[i].GetId() != newLogs[i-1].GetId()+1 {
+			return fmt.Errorf("log %d does not follow log %d", newLogs[i].GetId(), newLogs[i-1].GetId())
+		}
+	}
+
+	if len(newLogs) != 0 && len(logs) != 0 && newLogs[0].GetId() > lastIndex(logs)+1 {
+		return fmt.Errorf("log %d does not follow the last log %d", newLogs[0].GetId(), lastIndex(logs))
+	}
+
+	return nil
+}
+
+// deleteRange returns logs after deleting logs between min and max inclusive
+func deleteRange(logs []*pb.Entry, min, max uint64) ([]*pb.Entry, error) {
+	if len(logs) == 0 || min > max || max < firstIndex(logs) || min > lastIndex(logs) {
+		return logs, nil
+	}
+
+	switch {
+	case min <= firstIndex(logs) && max >= lastIndex(logs):
+		return []*pb.Entry{}, nil
+	case max >= lastIndex(logs):
+		return truncateLogs(logs, min-1), nil
+	case min <= firstIndex(logs):
+		// copy to release the underlying array of deleted logs
+		return append([]*pb.Entry{}, logs[max-firstIndex(logs)+1:]...), nil
+	default:
+		return nil, fmt.Errorf("cannot delete logs between %d and %d in the middle of logs", min, max)
+	}
+}
+
+// truncateLogs returns logs without logs after the given id
+func truncateLogs(logs []*pb.Entry, id uint64) []*pb.Entry {
+	switch {
+	case len(logs) == 0 || id >= lastIndex(logs):
+		return logs
+	case id < firstIndex(logs):
+		return logs[:0]
+	default:
+		return logs[:id-firstIndex(logs)+1]
+	}
 }
@@ -0,0 +1,118 @@
+package raft
+
+import (
+	"context"
+	"testing"
+
+	"github.com/justin0u0/raft/pb"
+	"go.uber.org/zap"
+)
+
+// countingPersister counts writes to the underlying persister
+type countingPersister struct {
+	*MemoryPersister
+	states  int
+	stores  int
+	deletes int
+}
+
+func (p *countingPersister) SetState(currentTerm uint64, votedFor uint32) error {
+	p.states++
+	return p.MemoryPersister.SetState(currentTerm, votedFor)
+}
+
+func (p *countingPersister) StoreLogs(logs []*pb.Entry) error {
+	p.stores++
+	return p.MemoryPersister.StoreLogs(logs)
+}
+
+func (p *countingPersister) DeleteRange(min, max uint64) error {
+	p.deletes++
+	return p.MemoryPersister.DeleteRange(min, max)
+}
+
+func (p *countingPersister) checkWrites(t *testing.T, states, stores, deletes int) {
+	if p.states != states || p.stores != stores || p.deletes != deletes {
+		t.Fatalf("expect %d state writes, %d stores and %d deletes, got %d, %d and %d",
+			states, stores, deletes, p.states, p.stores, p.deletes)
+	}
+}
+
+func TestSaveRaftStateIncrementally(t *testing.T) {
+	p := &countingPersister{MemoryPersister: NewMemoryPersister()}
+	rs := &raftState{logStore: p, stableStore: p, snapshotStore: p, storage: newStorage(p, zap.NewNop())}
+
+	rs.currentTerm = 1
+	if err := rs.appendLogs([]*pb.Entry{{Id: 1, Term: 1}, {Id: 2, Term: 1}, {Id: 3, Term: 1}}); err != nil {
+		t.Fatalf("fail to append logs: %v", err)
+	}
+	p.checkWrites(t, 1, 1, 0)
+
+	// nothing is written if nothing changes
+	if err := rs.saveRaftState(context.Background()); err != nil {
+		t.Fatalf("fail to save raft state: %v", err)
+	}
+	p.checkWrites(t, 1, 1, 0)
+
+	// only the new log is stored
+	if err := rs.appendLogs([]*pb.Entry{{Id: 4, Term: 1}}); err != nil {
+		t.Fatalf("fail to append logs: %v", err)
+	}
+	p.checkWrites(t, 1, 2, 0)
+
+	// conflicting logs are deleted before new logs are stored
+	rs.currentTerm = 2
+	if err := rs.deleteLogs(2); err != nil {
+		t.Fatalf("fail to delete logs: %v", err)
+	}
+	if err := rs.appendLogs([]*pb.Entry{{Id: 3, Term: 2}}); err != nil {
+		t.Fatalf("fail to append logs: %v", err)
+	}
+	p.checkWrites(t, 2, 3, 1)
+	checkPersistedLogs(t, p, 1, 1, 2)
+
+	loaded := &raftState{logStore: p, stableStore: p, snapshotStore: p, storage: newStorage(p, zap.NewNop())}
+	if err := loaded.loadRaftState(); err != nil {
+		t.Fatalf("fail to load raft state: %v", err)
+	}
+
+	if id, term := loaded.getLastLog(); loaded.currentTerm != 2 || id != 3 || term != 2 {
+		t.Fatalf("expect term 2 with the last log 3 of term 2, got term %d with the last log %d of term %d",
+			loaded.currentTerm, id, term)
+	}
+}
+
+func TestCompactLogs(t *testing.T) {
+	p := NewMemoryPersister()
+	rs := &raftState{
+		logStore:       p,
+		stableStore:    p,
+		snapshotStore:  p,
+		storage:        newStorage(p, zap.NewNop()),
+		sessionResults: make(map[uint64][]byte),
+	}
+
+	if err := rs.appendLogs([]*pb.Entry{{Id: 1, Term: 1}, {Id: 2, Term: 1}, {Id: 3, Term: 1}}); err != nil {
+		t.Fatalf("fail to append logs: %v", err)
+	}
+
+	// logs after the snapshot are retained
+	if err := rs.compactLogs(2, 1, nil, nil, []byte("snapshot")); err != nil {
+		t.Fatalf("fail to compact logs: %v", err)
+	}
+	if first, _ := p.FirstIndex(); first != 3 {
+		t.Fatalf("expect logs after the snapshot from 3, got logs from %d", first)
+	}
+
+	// logs are discarded if the last included log does not match
+	if err := rs.compactLogs(3, 2, nil, nil, []byte("snapshot")); err != nil {
+		t.Fatalf("fail to compact logs: %v", err)
+	}
+	if last, _ := p.LastIndex(); last != 0 {
+		t.Fatalf("expect no logs, got logs up to %d", last)
+	}
+
+	if id, term := rs.getLastLog(); id != 3 || term != 2 {
+		t.Fatalf("expect the last log 3 of term 2, got %d of term %d", id, term)
+	}
+}
@@ -0,0 +1,181 @@
+package raft
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/justin0u0/raft/pb"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// pipelineBufferSize is the number of AppendEntries in a pipeline waiting for responses
+const pipelineBufferSize = 64
+
+var errPipelineIdle = newRPCError(codes.Canceled, "pipeline is closed since it is idle")
+
+// pipeline is an AppendEntriesPipeline stream to a peer, requests are sent without waiting for responses,
+// and responses are matched with requests in the sending order
+type pipeline struct {
+	stream pb.Raft_AppendEntriesPipelineClient
+	cancel context.CancelFunc
+
+	// inflightCh queues requests waiting for responses
+	inflightCh chan *inflightRequest
+	// doneCh is closed once the pipeline is broken by err
+	doneCh   chan struct{}
+	err      error
+	failOnce sync.Once
+}
+
+type inflightRequest struct {
+	*replicationRequest
+	deadline time.Time
+}
+
+// sendPipelined sends the AppendEntries by the pipeline, the response is passed to the leader by the receiver,
+// and a broken pipeline is closed by the run loop
+func (rp *replicator) sendPipelined(ctx context.Context, req *replicationRequest) error {
+	if rp.pipeline != nil {
+		select {
+		case <-rp.pipeline.doneCh:
+			if err := rp.closePipeline(ctx); err != nil {
+				rp.deliverAppendEntries(ctx, req, nil, err)
+				return err
+			}
+
+			if !rp.pipelined {
+				return rp.sendAppendEntries(ctx, req)
+			}
+		default:
+		}
+	}
+
+	if rp.pipeline == nil {
+		p, err := rp.openPipeline(ctx)
+		if err != nil {
+			rp.deliverAppendEntries(ctx, req, nil, err)
+			return err
+		}
+
+		rp.pipeline = p
+	}
+
+	p := rp.pipeline
+
+	// the request is queued before it is sent, so the receiver always finds the request of a response
+	select {
+	case <-p.doneCh:
+		rp.deliverAppendEntries(ctx, req, nil, p.err)
+		return nil
+	case p.inflightCh <- &inflightRequest{replicationRequest: req, deadline: time.Now().Add(rp.timeout)}:
+	}
+
+	if err := p.stream.Send(req.appendEntries); err != nil {
+		// the receiver fails requests in flight once the stream is broken
+		p.cancel()
+	}
+
+	return nil
+}
+
+// openPipeline opens the stream with the context of the replicator, so the stream is closed once the leader steps down
+func (rp *replicator) openPipeline(ctx context.Context) (*pipeline, error) {
+	streamCtx, cancel := context.WithCancel(ctx)
+
+	stream, err := rp.peer.AppendEntriesPipeline(streamCtx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	p := &pipeline{
+		stream:     stream,
+		cancel:     cancel,
+		inflightCh: make(chan *inflightRequest, pipelineBufferSize),
+		doneCh:     make(chan struct{}),
+	}
+
+	go rp.receive(ctx, p)
+
+	return p, nil
+}
+
+// receive matches responses with requests in flight until the pipeline is broken,
+// the pipeline is broken if a response is not received before the deadline of its request
+func (rp *replicator) receive(ctx context.Context, p *pipeline) {
+	for {
+		var inflight *inflightRequest
+		select {
+		case <-ctx.Done():
+			p.fail(ctx.Err())
+			return
+		case <-p.doneCh:
+			return
+		case inflight = <-p.inflightCh:
+		}
+
+		timer := time.AfterFunc(time.Until(inflight.deadline), p.cancel)
+		resp, err := p.stream.Recv()
+		timer.Stop()
+
+		// the pipeline is broken before the failure is passed to the leader,
+		// so requests sent again by the leader are not queued to the broken pipeline
+		if err != nil {
+			p.fail(err)
+		}
+
+		rp.deliverAppendEntries(ctx, inflight.replicationRequest, resp, err)
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// fail breaks the pipeline by err, only the first error is kept
+func (p *pipeline) fail(err error) {
+	p.failOnce.Do(func() {
+		p.err = err
+		p.cancel()
+		close(p.doneCh)
+	})
+}
+
+// closePipeline fails requests left in the broken pipeline, and falls back to unary AppendEntries
+// if the peer does not support the pipeline
+func (rp *replicator) closePipeline(ctx context.Context) error {
+	p := rp.pipeline
+	rp.pipeline = nil
+
+	// requests are only queued by the run loop, and the receiver may take the last one before it exits
+	for drained := false; !drained; {
+		select {
+		case inflight := <-p.inflightCh:
+			rp.deliverAppendEntries(ctx, inflight.replicationRequest, nil, p.err)
+		default:
+			drained = true
+		}
+	}
+
+	if status.Code(p.err) == codes.Unimplemented {
+		rp.logger.Warn("AppendEntriesPipeline is not supported by the peer, fall back to AppendEntries", zap.Error(p.err))
+		rp.pipelined = false
+
+		return nil
+	}
+
+	return p.err
+}
+
+// closeIdlePipeline closes the pipeline that has not sent any request within the idle timeout,
+// so the stream does not stay open on a peer that the leader no longer replicates to
+func (rp *replicator) closeIdlePipeline(ctx context.Context) {
+	rp.logger.Debug("close idle pipeline")
+
+	rp.pipeline.stream.CloseSend()
+	rp.pipeline.fail(errPipelineIdle)
+	rp.closePipeline(ctx)
+}
@@ -0,0 +1,95 @@
+package raft
+
+import (
+	"context"
+	"time"
+
+	"github.com/justin0u0/raft/pb"
+	"go.uber.org/zap"
+)
+
+// RPC handlers
+
+// preVote grants the pre-vote if the server would vote for the candidate in the next term,
+// it does not change the term or the vote of the server
+func (r *Raft) preVote(req *pb.RequestVoteRequest) (*pb.RequestVoteResponse, error) {
+	// reject if the leader is still alive
+	if r.state == Leader || (r.state == Follower && time.Since(r.lastLeaderContact) < r.config.HeartbeatTimeout) {
+		r.logger.Info("reject pre-vote since the leader is still alive", zap.Uint32("candidate", req.GetCandidateId()))
+
+		return &pb.RequestVoteResponse{Term: r.currentTerm, VoteGranted: false}, nil
+	}
+
+	if req.GetTerm() == r.currentTerm && r.votedFor != 0 && r.votedFor != req.GetCandidateId() {
+		r.logger.Info("reject pre-vote since already vote for another candidate",
+			zap.Uint64("term", r.currentTerm),
+			zap.Uint32("votedFor", r.votedFor))
+
+		return &pb.RequestVoteResponse{Term: r.currentTerm, VoteGranted: false}, nil
+	}
+
+	if !r.isUpToDate(req.GetLastLogId(), req.GetLastLogTerm()) {
+		r.logger.Info("reject pre-vote since last entry is more up-to-date")
+
+		return &pb.RequestVoteResponse{Term: r.currentTerm, VoteGranted: false}, nil
+	}
+
+	r.logger.Info("grant pre-vote", zap.Uint32("candidate", req.GetCandidateId()), zap.Uint64("term", req.GetTerm()))
+
+	return &pb.RequestVoteResponse{Term: r.currentTerm, VoteGranted: true}, nil
+}
+
+// candidate related
+
+// runPreVote asks voters if the server could win the election in the next term without increasing its term,
+// it returns true if a quorum of voters grant the pre-vote
+func (r *Raft) runPreVote(ctx context.Context) bool {
+	grantedVotes := 1 // vote for itself
+	votesNeeded := r.quorumSize()
+
+	lastLogId, lastLogTerm := r.getLastLog()
+	req := &pb.RequestVoteRequest{
+		Term:        r.currentTerm + 1,
+		CandidateId: r.id,
+		LastLogId:   lastLogId,
+		LastLogTerm: lastLogTerm,
+		PreVote:     true,
+	}
+
+	voteCh := make(chan *voteResult, len(r.peers))
+	r.broadcastRequestVote(ctx, req, voteCh)
+
+	timeoutCh := randomTimeout(r.config.ElectionTimeout)
+
+	for r.state == Candidate && grantedVotes < votesNeeded {
+		select {
+		case <-ctx.Done():
+			return false
+
+		case vote := <-voteCh:
+			if vote.GetTerm() > r.currentTerm {
+				r.toFollower(vote.GetTerm())
+				r.logger.Info("receive new term on pre-vote response, fallback to follower", zap.Uint32("peer", vote.peerId))
+
+				return false
+			}
+
+			if vote.GetVoteGranted() {
+				grantedVotes++
+				r.logger.Info("pre-vote granted", zap.Uint32("peer", vote.peerId), zap.Int("grantedVote", grantedVotes))
+			}
+
+		case <-timeoutCh:
+			// wait for another heartbeat timeout to start a new pre-vote
+			r.toFollower(r.currentTerm)
+			r.logger.Info("pre-vote timeout reached, fallback to follower")
+
+			return false
+
+		case rpc := <-r.rpcCh:
+			r.handleRPCRequest(rpc)
+		}
+	}
+
+	return r.state == Candidate
+}
@@ -2,6 +2,7 @@ package raft
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/justin0u0/raft/pb"
@@ -12,7 +13,6 @@ type Raft struct {
 	pb.UnimplementedRaftServer
 
 	*raftState
-	persister Persister
 
 	id    uint32
 	peers map[uint32]Peer
@@ -21,54 +21,198 @@ type Raft struct {
 	logger *zap.Logger
 
 	lastHeartbeat time.Time
+	// lastLeaderContact is the last time receiving a request from the leader
+	lastLeaderContact time.Time
+
+	// lastContact is the last time receiving a response from each peer, only used by the leader
+	lastContact map[uint32]time.Time
+	// lostQuorum is true after the leader steps down by losing contact with a quorum until it hears from
+	// a leader again, the server runs the pre-vote before elections so it does not disrupt the cluster
+	// by increasing its term while it is partitioned
+	lostQuorum bool
+
+	// round is the number of heartbeat rounds broadcasted by the leader
+	round uint64
+	// ackedRounds is the latest heartbeat round responded by each peer, only used by the leader
+	ackedRounds map[uint32]uint64
+	// lastAck is the time sending the latest heartbeat acked by each peer, only used by the leader
+	lastAck map[uint32]time.Time
+	// progress is the replication progress of each peer, only used by the leader
+	progress map[uint32]*progress
+	// replicators are replicators of peers started in the current term, only used by the leader
+	replicators map[uint32]*replicator
+	// leaseState is the state of the leader lease, protected by mu
+	leaseState LeaseState
+	// pendingReads are reads waiting for the leader to confirm its leadership
+	pendingReads []*pendingRead
+
+	// sessionActivity is the last time receiving a command from each client, only used by the leader
+	sessionActivity map[uint64]time.Time
+
+	// leadershipTransfer is the in progress leadership transfer, it is started by the leader
+	leadershipTransfer *leadershipTransfer
+	// transferElection is true if the election is started by TimeoutNow
+	transferElection bool
 
 	// rpcCh stores incoming RPCs
 	rpcCh chan *rpc
-	// applyCh stores logs that can be applied
-	applyCh chan *pb.Entry
+	// doneCh is closed once Run returns, so RPC handlers stop waiting for the stopped server
+	doneCh chan struct{}
+	// replicateCh notifies the leader to replicate new logs, pending notifications are coalesced
+	replicateCh chan struct{}
+	// fsm is the state machine driven by the apply goroutine
+	fsm FSM
+	// applyNotifyCh notifies the apply goroutine to apply committed logs, pending notifications are coalesced
+	applyNotifyCh chan struct{}
+	// fsmSnapshotCh stores requests to snapshot the FSM
+	fsmSnapshotCh chan *fsmSnapshotRequest
+	// applyCh stores logs that can be applied, and snapshotCh stores snapshots that should be restored
+	// by the application, they are nil if NewRaft is given an FSM
+	applyCh    chan *pb.Entry
+	snapshotCh chan *Snapshot
 }
 
 var _ pb.RaftServer = (*Raft)(nil)
 
-func NewRaft(id uint32, peers map[uint32]Peer, persister Persister, config *Config, logger *zap.Logger) *Raft {
+// NewRaft creates the raft server, committed commands are applied to the FSM,
+// or delivered to ApplyCh if the FSM is nil.
+//
+// Only results returned by the FSM are responded to clients waiting on ApplyCommand,
+// commands delivered to ApplyCh are responded with an empty result once they are received.
+func NewRaft(id uint32, peers map[uint32]Peer, persister Persister, fsm FSM, config *Config, logger *zap.Logger) *Raft {
+	var applyCh chan *pb.Entry
+	var snapshotCh chan *Snapshot
+	if fsm == nil {
+		f := newChannelFSM()
+		fsm, applyCh, snapshotCh = f, f.applyCh, f.snapshotCh
+	}
+
+	configuration := newConfiguration(id, peers)
+	if config.Join {
+		configuration = &pb.Configuration{}
+	}
+
 	raftState := &raftState{
-		state:       Follower,
-		currentTerm: 0,
-		votedFor:    0,
-		logs:        make([]*pb.Entry, 0),
-		commitIndex: 0,
-		lastApplied: 0,
-		nextIndex:   make(map[uint32]uint64),
-		matchIndex:  make(map[uint32]uint64),
+		state:                     Follower,
+		currentTerm:               0,
+		votedFor:                  0,
+		logStore:                  persister,
+		stableStore:               persister,
+		snapshotStore:             persister,
+		storage:                   newStorage(persister, logger.With(zap.Uint32("id", id))),
+		lastIncludedConfiguration: configuration,
+		commitIndex:               0,
+		lastApplied:               0,
+		appliedCh:                 make(chan struct{}),
+		configuration:             configuration,
+		nextIndex:                 make(map[uint32]uint64),
+		matchIndex:                make(map[uint32]uint64),
+		futures:                   make(map[uint64]*applyFuture),
+		sessions:                  make(map[uint64]*pb.Session),
+		sessionResults:            make(map[uint64][]byte),
 	}
 
 	return &Raft{
-		raftState:     raftState,
-		persister:     persister,
-		id:            id,
-		peers:         peers,
-		config:        config,
-		logger:        logger.With(zap.Uint32("id", id)),
-		lastHeartbeat: time.Now(),
-		rpcCh:         make(chan *rpc),
-		applyCh:       make(chan *pb.Entry),
+		raftState:       raftState,
+		id:              id,
+		peers:           peers,
+		config:          config,
+		logger:          logger.With(zap.Uint32("id", id)),
+		lastHeartbeat:   time.Now(),
+		lastContact:     make(map[uint32]time.Time),
+		ackedRounds:     make(map[uint32]uint64),
+		lastAck:         make(map[uint32]time.Time),
+		progress:        make(map[uint32]*progress),
+		sessionActivity: make(map[uint64]time.Time),
+		rpcCh:           make(chan *rpc),
+		doneCh:          make(chan struct{}),
+		replicateCh:     make(chan struct{}, 1),
+		fsm:             fsm,
+		applyNotifyCh:   make(chan struct{}, 1),
+		fsmSnapshotCh:   make(chan *fsmSnapshotRequest),
+		applyCh:         applyCh,
+		snapshotCh:      snapshotCh,
 	}
 }
 
 // RPC handlers
 
-func (r *Raft) applyCommand(req *pb.ApplyCommandRequest) (*pb.ApplyCommandResponse, error) {
+func (r *Raft) applyCommand(req *applyCommandRequest) (*pb.ApplyCommandResponse, error) {
 	if r.state != Leader {
-		return nil, errNotLeader
+		return nil, r.notLeaderError()
+	}
+
+	// stop accepting new commands so the target can catch up
+	if r.leadershipTransfer != nil {
+		return nil, errLeadershipTransferring
+	}
+
+	if req.GetClientId() != 0 {
+		r.sessionActivity[req.GetClientId()] = time.Now()
+
+		// the command is already applied, respond the cached result instead of appending it again
+		if session := r.getSession(req.GetClientId()); session != nil && req.GetSequence() <= session.GetSequence() {
+			if req.GetSequence() != session.GetSequence() {
+				return nil, errSequenceOutdated
+			}
+
+			return &pb.ApplyCommandResponse{Result: session.GetResult()}, nil
+		}
 	}
 
 	lastLogId, _ := r.getLastLog()
-	e := &pb.Entry{Id: lastLogId + 1, Term: r.currentTerm, Data: req.GetData()}
-	r.appendLogs([]*pb.Entry{e})
+	e := &pb.Entry{
+		Id:        lastLogId + 1,
+		Term:      r.currentTerm,
+		Data:      req.GetData(),
+		ClientId:  req.GetClientId(),
+		Sequence:  req.GetSequence(),
+		Timestamp: time.Now().UnixNano(),
+		Headers:   req.GetHeaders(),
+	}
+	if err := r.appendLogs([]*pb.Entry{e}); err != nil {
+		return nil, fmt.Errorf("fail to append logs: %w", err)
+	}
+	r.notifyReplicate()
+
+	if req.future != nil {
+		req.future.term = e.GetTerm()
+		r.addFuture(e.GetId(), req.future)
+	}
 
 	return &pb.ApplyCommandResponse{Entry: e}, nil
 }
 
+// forwardApplyCommand forwards the command to the leader and responds the response of the leader
+func (r *Raft) forwardApplyCommand(rpc *rpc, req *applyCommandRequest) {
+	peer, ok := r.peers[r.leaderId]
+	if !ok || r.leaderId == r.id {
+		rpc.respond(nil, r.notLeaderError())
+		return
+	}
+
+	leaderId := r.leaderId
+
+	go func() {
+		resp, err := peer.ApplyCommand(rpc.ctx, req.ApplyCommandRequest)
+		if err != nil {
+			r.logger.Error("fail to forward ApplyCommand RPC", zap.Error(err), zap.Uint32("leader", leaderId))
+			rpc.respond(nil, err)
+
+			return
+		}
+
+		// the leader already waits for the command to be applied
+		if req.future != nil {
+			req.future.respCh <- resp.GetResult()
+		}
+
+		rpc.respond(resp, nil)
+	}()
+
+	r.logger.Debug("forward command to the leader", zap.Uint32("leader", leaderId))
+}
+
 func (r *Raft) appendEntries(req *pb.AppendEntriesRequest) (*pb.AppendEntriesResponse, error) {
 	if req.GetTerm() < r.currentTerm {
 		r.logger.Info("reject append entries since current term is older")
@@ -77,6 +221,8 @@ func (r *Raft) appendEntries(req *pb.AppendEntriesRequest) (*pb.AppendEntriesRes
 	}
 
 	r.lastHeartbeat = time.Now()
+	r.lastLeaderContact = r.lastHeartbeat
+	r.lostQuorum = false
 
 	// increase term if receive a newer one
 	if req.GetTerm() > r.currentTerm {
@@ -89,50 +235,116 @@ func (r *Raft) appendEntries(req *pb.AppendEntriesRequest) (*pb.AppendEntriesRes
 		r.logger.Info("receive request from leader, fallback to follower", zap.Uint64("term", r.currentTerm))
 	}
 
-	// verify the last log entry
+	if r.leaderId != req.GetLeaderId() {
+		r.setLeader(req.GetLeaderId())
+		r.logger.Info("found the leader of current term", zap.Uint32("leader", r.leaderId))
+
+		r.finishLeadershipTransfer()
+	}
+
 	prevLogId := req.GetPrevLogId()
 	prevLogTerm := req.GetPrevLogTerm()
+	entries := req.GetEntries()
+
+	// logs before and including `lastIncludedId` are already committed and compacted, skip them
+	if prevLogId < r.lastIncludedId {
+		skip := r.lastIncludedId - prevLogId
+		if skip < uint64(len(entries)) {
+			entries = entries[skip:]
+		} else {
+			entries = nil
+		}
+
+		prevLogId, prevLogTerm = r.lastIncludedId, r.lastIncludedTerm
+	}
+
+	// verify the last log entry
 	if prevLogId != 0 && prevLogTerm != 0 {
-		log := r.getLog(prevLogId)
+		logTerm, _ := r.getLogTerm(prevLogId)
 
-		if prevLogTerm != log.GetTerm() {
+		if prevLogTerm != logTerm {
 			r.logger.Info("the given previous log from leader is missing or mismatched",
 				zap.Uint64("prevLogId", prevLogId),
 				zap.Uint64("prevLogTerm", prevLogTerm),
-				zap.Uint64("logTerm", log.GetTerm()))
+				zap.Uint64("logTerm", logTerm))
+
+			conflictTerm, conflictIndex := r.findConflict(prevLogId)
 
-			return &pb.AppendEntriesResponse{Term: r.currentTerm, Success: false}, nil
+			return &pb.AppendEntriesResponse{
+				Term:          r.currentTerm,
+				Success:       false,
+				ConflictTerm:  conflictTerm,
+				ConflictIndex: conflictIndex,
+			}, nil
 		}
 	}
 
-	if len(req.GetEntries()) != 0 {
-		// delete entries after previous log
-		r.deleteLogs(prevLogId)
+	// find the first new entry that is conflicting or missing,
+	// so an outdated request does not delete logs that are already appended
+	for i, entry := range entries {
+		if logTerm, ok := r.getLogTerm(entry.GetId()); ok && logTerm == entry.GetTerm() {
+			continue
+		}
+
+		// delete the conflicting entry and all that follow it
+		if err := r.deleteLogs(entry.GetId() - 1); err != nil {
+			return nil, fmt.Errorf("fail to delete logs: %w", err)
+		}
 
 		// append new entries
-		r.appendLogs(req.GetEntries())
+		if err := r.appendLogs(entries[i:]); err != nil {
+			return nil, fmt.Errorf("fail to append logs: %w", err)
+		}
+		r.syncPeers()
 
 		r.logger.Info("receive and append new entries",
-			zap.Int("newEntries", len(req.GetEntries())),
-			zap.Int("numberOfEntries", len(r.logs)),
+			zap.Int("newEntries", len(entries)-i),
+			zap.Uint64("lastLogId", r.getLastLogId()),
 		)
+
+		break
 	}
 
-	if req.GetLeaderCommitId() > r.commitIndex {
-		lastLogId, _ := r.getLastLog()
-		if req.GetLeaderCommitId() < lastLogId {
-			r.setCommitIndex(req.GetLeaderCommitId())
-		} else {
-			r.setCommitIndex(lastLogId)
-		}
+	// the heartbeat sent while entries are in flight may only carry logs before the commit index,
+	// so the commit index is never moved backwards
+	newCommitIndex := req.GetLeaderCommitId()
+	if lastNewLogId := prevLogId + uint64(len(entries)); lastNewLogId < newCommitIndex {
+		newCommitIndex = lastNewLogId
+	}
+
+	if newCommitIndex > r.commitIndex {
+		r.setCommitIndex(newCommitIndex)
 
 		r.logger.Info("update commit index from leader", zap.Uint64("commitIndex", r.commitIndex))
-		go r.applyLogs(r.applyCh)
+		r.notifyApply()
 	}
 
 	return &pb.AppendEntriesResponse{Term: r.currentTerm, Success: true}, nil
 }
 
+// findConflict finds the term of the conflicting log and the first log id of that term,
+// so the leader can skip all logs of the conflicting term at once
+func (r *Raft) findConflict(prevLogId uint64) (conflictTerm, conflictIndex uint64) {
+	lastLogId, _ := r.getLastLog()
+	if prevLogId > lastLogId {
+		// the previous log is missing
+		return 0, lastLogId + 1
+	}
+
+	conflictTerm, _ = r.getLogTerm(prevLogId)
+	conflictIndex = prevLogId
+
+	for conflictIndex-1 > r.lastIncludedId {
+		if term, _ := r.getLogTerm(conflictIndex - 1); term != conflictTerm {
+			break
+		}
+
+		conflictIndex--
+	}
+
+	return conflictTerm, conflictIndex
+}
+
 func (r *Raft) requestVote(req *pb.RequestVoteRequest) (*pb.RequestVoteResponse, error) {
 	// reject if current term is older
 	if req.GetTerm() < r.currentTerm {
@@ -141,6 +353,25 @@ func (r *Raft) requestVote(req *pb.RequestVoteRequest) (*pb.RequestVoteResponse,
 		return &pb.RequestVoteResponse{Term: r.currentTerm, VoteGranted: false}, nil
 	}
 
+	if req.GetPreVote() {
+		return r.preVote(req)
+	}
+
+	// reject without increasing term if the leader is still alive,
+	// so a server removed from the configuration can not disrupt the cluster,
+	// unless the election is started by the leader to transfer its leadership
+	if r.state == Follower && !req.GetLeadershipTransfer() && time.Since(r.lastLeaderContact) < r.config.HeartbeatTimeout {
+		r.logger.Info("reject request vote since the leader is still alive", zap.Uint32("candidate", req.GetCandidateId()))
+
+		return &pb.RequestVoteResponse{Term: r.currentTerm, VoteGranted: false}, nil
+	}
+
+	if r.state == Leader && !r.isVoter(req.GetCandidateId()) {
+		r.logger.Info("reject request vote since the candidate is not a voter", zap.Uint32("candidate", req.GetCandidateId()))
+
+		return &pb.RequestVoteResponse{Term: r.currentTerm, VoteGranted: false}, nil
+	}
+
 	// increase term if receive a newer one
 	if req.GetTerm() > r.currentTerm {
 		r.toFollower(req.GetTerm())
@@ -156,10 +387,8 @@ func (r *Raft) requestVote(req *pb.RequestVoteRequest) (*pb.RequestVoteResponse,
 		return &pb.RequestVoteResponse{Term: r.currentTerm, VoteGranted: false}, nil
 	}
 
-	lastLogId, lastLogTerm := r.getLastLog()
-
 	// reject if last log entry is more up-to-date
-	if lastLogTerm > req.GetLastLogTerm() || (lastLogTerm == req.GetLastLogTerm() && lastLogId > req.GetLastLogId()) {
+	if !r.isUpToDate(req.GetLastLogId(), req.GetLastLogTerm()) {
 		r.logger.Info("reject since last entry is more up-to-date")
 
 		return &pb.RequestVoteResponse{Term: r.currentTerm, VoteGranted: false}, nil
@@ -172,18 +401,39 @@ func (r *Raft) requestVote(req *pb.RequestVoteRequest) (*pb.RequestVoteResponse,
 	return &pb.RequestVoteResponse{Term: r.currentTerm, VoteGranted: true}, nil
 }
 
+// isUpToDate checks if the given last log is at least as up-to-date as the last log of the server
+func (r *Raft) isUpToDate(lastLogId, lastLogTerm uint64) bool {
+	id, term := r.getLastLog()
+
+	return lastLogTerm > term || (lastLogTerm == term && lastLogId >= id)
+}
+
 // raft main loop
 
 func (r *Raft) Run(ctx context.Context) {
-	if err := r.loadRaftState(r.persister); err != nil {
+	defer close(r.doneCh)
+
+	if err := r.loadRaftState(); err != nil {
 		r.logger.Error("fail to load raft state", zap.Error(err))
 		return
 	}
 
+	if r.lastIncludedId != 0 {
+		if err := r.restoreLastSnapshot(); err != nil {
+			r.logger.Error("fail to restore snapshot", zap.Error(err))
+			return
+		}
+	}
+
+	r.syncPeers()
+
+	go r.storage.run(ctx)
+	go r.runApply(ctx)
+
 	r.logger.Info("starting raft",
 		zap.Uint64("term", r.currentTerm),
 		zap.Uint32("votedFor", r.votedFor),
-		zap.Int("logs", len(r.logs)))
+		zap.Uint64("lastLogId", r.getLastLogId()))
 
 	for {
 		select {
@@ -204,6 +454,11 @@ func (r *Raft) Run(ctx context.Context) {
 	}
 }
 
+// ApplyCh returns the channel of committed commands that the application must apply,
+// it is nil if NewRaft is given an FSM.
+//
+// Only logs of EntryType_COMMAND are delivered, configurations, no-ops and session expiries are internal to raft.
+// The entry carries the time it was appended by the leader and the headers given by the client.
 func (r *Raft) ApplyCh() <-chan *pb.Entry {
 	return r.applyCh
 }
@@ -227,6 +482,9 @@ func (r *Raft) runFollower(ctx context.Context) {
 				r.handleFollowerHeartbeatTimeout()
 			}
 
+		case <-r.leadershipTransferTimeoutCh():
+			r.handleLeadershipTransferTimeout()
+
 		case rpc := <-r.rpcCh:
 			r.handleRPCRequest(rpc)
 		}
@@ -234,6 +492,12 @@ func (r *Raft) runFollower(ctx context.Context) {
 }
 
 func (r *Raft) handleFollowerHeartbeatTimeout() {
+	// a server that is not a voter should wait to be added or promoted instead of starting an election
+	if !r.isVoter(r.id) {
+		r.logger.Debug("heartbeat timeout, but not a voter")
+		return
+	}
+
 	r.toCandidate()
 
 	r.logger.Info("heartbeat timeout, change state from follower to candidate")
@@ -249,15 +513,36 @@ type voteResult struct {
 func (r *Raft) runCandidate(ctx context.Context) {
 	r.logger.Info("running candidate")
 
+	// an election started by the leader through TimeoutNow is expected to be won, skip the pre-vote
+	if (r.config.PreVote || r.lostQuorum) && !r.transferElection && !r.runPreVote(ctx) {
+		return
+	}
+
 	grantedVotes := 0
-	votesNeeded := (len(r.peers)+1)/2 + 1
+	votesNeeded := r.quorumSize()
 
-	// vote for itself
+	// vote for itself, which wins the election if it is the only voter
 	r.voteForSelf(&grantedVotes)
+	if grantedVotes >= votesNeeded {
+		r.toLeader()
+		r.logger.Info("election won as the only voter", zap.Uint64("term", r.currentTerm))
+
+		return
+	}
 
 	// request votes from peers
+	lastLogId, lastLogTerm := r.getLastLog()
+	req := &pb.RequestVoteRequest{
+		Term:               r.currentTerm,
+		CandidateId:        r.id,
+		LastLogId:          lastLogId,
+		LastLogTerm:        lastLogTerm,
+		LeadershipTransfer: r.transferElection,
+	}
+	r.transferElection = false
+
 	voteCh := make(chan *voteResult, len(r.peers))
-	r.broadcastRequestVote(ctx, voteCh)
+	r.broadcastRequestVote(ctx, req, voteCh)
 
 	// wait until:
 	// 1. it wins the election
@@ -278,6 +563,9 @@ func (r *Raft) runCandidate(ctx context.Context) {
 			r.logger.Info("election timeout reached, restarting election")
 			return
 
+		case <-r.leadershipTransferTimeoutCh():
+			r.handleLeadershipTransferTimeout()
+
 		case rpc := <-r.rpcCh:
 			r.handleRPCRequest(rpc)
 		}
@@ -291,22 +579,18 @@ func (r *Raft) voteForSelf(grantedVotes *int) {
 	r.logger.Info("vote for self", zap.Uint64("term", r.currentTerm))
 }
 
-func (r *Raft) broadcastRequestVote(ctx context.Context, voteCh chan *voteResult) {
-	r.logger.Info("broadcast request vote", zap.Uint64("term", r.currentTerm))
+func (r *Raft) broadcastRequestVote(ctx context.Context, req *pb.RequestVoteRequest, voteCh chan *voteResult) {
+	r.logger.Info("broadcast request vote", zap.Uint64("term", req.GetTerm()), zap.Bool("preVote", req.GetPreVote()))
 
-	lastLogId, lastLogTerm := r.getLastLog()
-
-	req := &pb.RequestVoteRequest{
-		Term:        r.currentTerm,
-		CandidateId: r.id,
-		LastLogId:   lastLogId,
-		LastLogTerm: lastLogTerm,
-	}
-
-	for peerId, peer := range r.peers {
+	for peerId, peer := range r.getPeers() {
 		peerId := peerId
 		peer := peer
 
+		// learners do not vote
+		if !r.isVoter(peerId) {
+			continue
+		}
+
 		go func() {
 			resp, err := peer.RequestVote(ctx, req)
 			if err != nil {
@@ -340,22 +624,37 @@ func (r *Raft) handleVoteResult(vote *voteResult, grantedVotes *int, votesNeeded
 
 // leader related
 
-type appendEntriesResult struct {
-	*pb.AppendEntriesResponse
-	req    *pb.AppendEntriesRequest
-	peerId uint32
-}
-
 func (r *Raft) runLeader(ctx context.Context) {
 	timeoutCh := randomTimeout(r.config.HeartbeatInterval)
+	checkQuorumCh := time.After(r.config.ElectionTimeout)
+	sessionExpiryCh := r.sessionExpiryCh()
+
+	// replicators of the term stop once the leader steps down
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	r.replicators = make(map[uint32]*replicator)
 
 	appendEntriesResultCh := make(chan *appendEntriesResult, len(r.peers))
+	installSnapshotResultCh := make(chan *installSnapshotResult, len(r.peers))
 
-	// reset `nextIndex` and `matchIndex`
+	// reset `nextIndex` and `matchIndex`, and probe where logs of peers match
 	lastLogId, _ := r.getLastLog()
+	for peerId := range r.getPeers() {
+		r.resetProgress(peerId, lastLogId+1, 0)
+	}
+
+	r.setLeader(r.id)
+
+	// the transfer started in a previous term fails since the server is elected again
+	r.finishLeadershipTransfer()
+
+	// logs of previous terms are committed with the no-op without waiting for a client command
+	r.appendNoOp()
+
+	// peers are assumed to be contacted when the leader is elected
+	now := time.Now()
 	for peerId := range r.peers {
-		r.nextIndex[peerId] = lastLogId + 1
-		r.matchIndex[peerId] = 0
+		r.lastContact[peerId] = now
 	}
 
 	for r.state == Leader {
@@ -366,92 +665,79 @@ func (r *Raft) runLeader(ctx context.Context) {
 		case <-timeoutCh:
 			timeoutCh = randomTimeout(r.config.HeartbeatInterval)
 
-			r.broadcastAppendEntries(ctx, appendEntriesResultCh)
-
-		case result := <-appendEntriesResultCh:
-			r.handleAppendEntriesResult(result)
+			r.broadcastAppendEntries(ctx, appendEntriesResultCh, installSnapshotResultCh)
+			// the leader of a single voter cluster holds the lease without heartbeats
+			r.extendLease()
 
-		case rpc := <-r.rpcCh:
-			r.handleRPCRequest(rpc)
-		}
-	}
-}
-
-func (r *Raft) broadcastAppendEntries(ctx context.Context, appendEntriesResultCh chan *appendEntriesResult) {
-	r.logger.Info("broadcast append entries")
+		case <-r.replicateCh:
+			// logs appended since the last notification are sent together
+			for peerId, peer := range r.getPeers() {
+				r.replicate(ctx, peerId, peer, 0, time.Time{}, appendEntriesResultCh, installSnapshotResultCh)
+			}
 
-	for peerId, peer := range r.peers {
-		peerId := peerId
-		peer := peer
+		case <-checkQuorumCh:
+			checkQuorumCh = time.After(r.config.ElectionTimeout)
 
-		prevLog := r.getLog(r.nextIndex[peerId] - 1)
-		entries := r.getLogs(r.nextIndex[peerId])
+			r.checkQuorum()
 
-		req := &pb.AppendEntriesRequest{
-			Term:           r.currentTerm,
-			LeaderId:       r.id,
-			LeaderCommitId: r.commitIndex,
-			Entries:        entries,
-		}
+		case <-sessionExpiryCh:
+			sessionExpiryCh = r.sessionExpiryCh()
 
-		if prevLog != nil {
-			req.PrevLogId = prevLog.GetId()
-			req.PrevLogTerm = prevLog.GetTerm()
-		}
+			r.expireSessions()
 
-		// r.logger.Debug("send append entries", zap.Uint32("peer", peerId), zap.Any("request", req), zap.Int("entries", len(entries)))
+		case result := <-appendEntriesResultCh:
+			r.handleAppendEntriesResult(result)
 
-		go func() {
-			resp, err := peer.AppendEntries(ctx, req)
-			if err != nil {
-				r.logger.Error("fail to send AppendEntries RPC", zap.Error(err), zap.Uint32("peer", peerId))
-				// connection issue, should not be handled
-				return
+			// keep sending entries as the inflight window frees up
+			if peer, ok := r.getPeers()[result.peerId]; ok && r.state == Leader {
+				r.replicate(ctx, result.peerId, peer, 0, time.Time{}, appendEntriesResultCh, installSnapshotResultCh)
 			}
 
-			appendEntriesResultCh <- &appendEntriesResult{
-				AppendEntriesResponse: resp,
-				req:                   req,
-				peerId:                peerId,
-			}
-		}()
-	}
-}
+		case <-r.storage.durableCh:
+			// the leader counts itself for logs that are durable
+			r.advanceCommitIndex()
 
-func (r *Raft) handleAppendEntriesResult(result *appendEntriesResult) {
-	peerId := result.peerId
-	logger := r.logger.With(zap.Uint32("peer", peerId))
+		case result := <-installSnapshotResultCh:
+			r.handleInstallSnapshotResult(result)
 
-	if result.GetTerm() > r.currentTerm {
-		r.toFollower(result.GetTerm())
-		logger.Info("receive new term on AppendEntries response, fallback to follower")
+		case <-r.leadershipTransferTimeoutCh():
+			r.handleLeadershipTransferTimeout()
 
-		return
+		case rpc := <-r.rpcCh:
+			r.handleRPCRequest(rpc)
+		}
 	}
 
-	entries := result.req.GetEntries()
+	r.abortReads()
+	r.revokeLease()
+	r.failFutures(errLeadershipLost)
 
-	if !result.GetSuccess() {
-		// if failed, decrease `nextIndex` and retry
-		nextIndex := r.nextIndex[peerId] - 1
-		matchIndex := r.matchIndex[peerId]
-		r.setNextAndMatchIndex(peerId, nextIndex, matchIndex)
+	if r.leaderId == r.id {
+		r.setLeader(0)
+	}
 
-		logger.Info("append entries failed, decrease next index",
-			zap.Uint64("nextIndex", nextIndex),
-			zap.Uint64("matchIndex", matchIndex))
-	} else if len(entries) != 0 {
-		// if successful and with log entries, update `matchIndex` and `nextIndex` for the follower
-		matchIndex := entries[len(entries)-1].GetId()
-		nextIndex := matchIndex + 1
-		r.setNextAndMatchIndex(peerId, nextIndex, matchIndex)
+	// the leadership transfer finishes once the new leader is known
+	r.finishLeadershipTransfer()
+}
 
-		logger.Info("append entries successfully, set next index and match index",
-			zap.Uint64("nextIndex", nextIndex),
-			zap.Uint64("matchIndex", matchIndex))
+// appendNoOp appends a no-op of the current term, which is not delivered to the application
+func (r *Raft) appendNoOp() {
+	lastLogId, _ := r.getLastLog()
+	e := &pb.Entry{Id: lastLogId + 1, Term: r.currentTerm, Type: pb.EntryType_NO_OP, Timestamp: time.Now().UnixNano()}
+	if err := r.appendLogs([]*pb.Entry{e}); err != nil {
+		r.logger.Error("fail to append no-op", zap.Error(err))
+		return
 	}
+	r.notifyReplicate()
 
-	replicasNeeded := (len(r.peers)+1)/2 + 1
+	r.logger.Info("append no-op", zap.Uint64("logId", e.GetId()))
+}
+
+// advanceCommitIndex commits the latest log of the current term replicated on a quorum of voters,
+// the leader is counted only if the log is durable on itself, and steps down if it is removed
+func (r *Raft) advanceCommitIndex() {
+	replicasNeeded := r.quorumSize()
+	syncedLogId := r.storage.getSyncedLogId()
 
 	logs := r.getLogs(r.commitIndex + 1)
 	for i := len(logs) - 1; i >= 0; i-- {
@@ -460,9 +746,14 @@ func (r *Raft) handleAppendEntriesResult(result *appendEntriesResult) {
 			continue
 		}
 
-		replicas := 1
-		for peerId := range r.peers {
-			if r.matchIndex[peerId] >= log.GetId() {
+		// only voters are counted, the leader is not counted if it is removed from the configuration
+		replicas := 0
+		for _, server := range r.configuration.GetServers() {
+			if server.GetSuffrage() != pb.Suffrage_VOTER {
+				continue
+			}
+
+			if (server.GetId() == r.id && syncedLogId >= log.GetId()) || r.matchIndex[server.GetId()] >= log.GetId() {
 				replicas++
 			}
 		}
@@ -471,9 +762,36 @@ func (r *Raft) handleAppendEntriesResult(result *appendEntriesResult) {
 			r.setCommitIndex(log.GetId())
 			r.logger.Info("found new logs committed, apply new logs", zap.Uint64("commitIndex", r.commitIndex))
 
-			go r.applyLogs(r.applyCh)
+			r.notifyApply()
 
 			break
 		}
 	}
+
+	// step down once the configuration that removes the leader is committed
+	if r.configurationId <= r.commitIndex && !r.isVoter(r.id) {
+		r.toFollower(r.currentTerm)
+		r.logger.Info("removed from the configuration, step down")
+	}
+}
+
+// checkQuorum steps down if the leader has not heard from a quorum of voters within an election timeout,
+// so a leader in the minority side of a partition stops accepting commands that can never be committed
+func (r *Raft) checkQuorum() {
+	contacted := 0
+	for _, server := range r.configuration.GetServers() {
+		if server.GetSuffrage() != pb.Suffrage_VOTER {
+			continue
+		}
+
+		if server.GetId() == r.id || time.Since(r.lastContact[server.GetId()]) < r.config.ElectionTimeout {
+			contacted++
+		}
+	}
+
+	if contacted < r.quorumSize() {
+		r.toFollower(r.currentTerm)
+		r.lostQuorum = true
+		r.logger.Info("lose contact with a quorum of voters, step down", zap.Int("contacted", contacted))
+	}
 }
@@ -1,11 +1,18 @@
 package raft
 
 import (
+	"context"
+	"errors"
 	"math/rand"
+	"runtime"
 	"strconv"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/justin0u0/raft/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestInitialElection(t *testing.T) {
@@ -99,13 +106,116 @@ func TestSingleLogReplication(t *testing.T) {
 	leaderId, leaderTerm := c.checkSingleLeader()
 
 	data := []byte("command 1")
-	c.applyCommand(leaderId, leaderTerm, data)
+	logId := c.applyCommand(leaderId, leaderTerm, data)
 
 	time.Sleep(500 * time.Millisecond)
 
 	for id := 1; id <= numNodes; id++ {
 		id := uint32(id)
-		c.checkLog(id, 1, leaderTerm, nil)
+		c.checkLog(id, logId, leaderTerm, nil)
+	}
+}
+
+func TestEntryMetadataReplication(t *testing.T) {
+	numNodes := 3
+
+	c := newCluster(t, numNodes)
+	defer c.stopAll()
+
+	time.Sleep(1 * time.Second)
+	leaderId, _ := c.checkSingleLeader()
+
+	start := time.Now()
+	headers := map[string]string{"trace-id": "abc"}
+	resp, err := c.rafts[leaderId].ApplyCommand(context.Background(), &pb.ApplyCommandRequest{
+		Data:     []byte("command 1"),
+		ClientId: 1,
+		Sequence: 1,
+		Headers:  headers,
+	})
+	if err != nil {
+		t.Fatal("fail to apply command:", err)
+	}
+	logId := resp.GetEntry().GetId()
+
+	time.Sleep(500 * time.Millisecond)
+
+	for id := 1; id <= numNodes; id++ {
+		id := uint32(id)
+		l := c.consumers[id].getLog(logId)
+		if l == nil {
+			t.Fatalf("log %d at server %d is not commited", logId, id)
+		}
+
+		if l.GetType() != pb.EntryType_COMMAND || l.GetClientId() != 1 {
+			t.Fatalf("commited log %d at server %d has type %s and client %d", logId, id, l.GetType(), l.GetClientId())
+		}
+		if l.GetTimestamp() < start.UnixNano() || l.GetTimestamp() > time.Now().UnixNano() {
+			t.Fatalf("commited log %d at server %d has timestamp %d not set by the leader", logId, id, l.GetTimestamp())
+		}
+		if l.GetHeaders()["trace-id"] != "abc" {
+			t.Fatalf("commited log %d at server %d has headers %v mismatched the given headers", logId, id, l.GetHeaders())
+		}
+	}
+}
+
+func TestSingleNodeCluster(t *testing.T) {
+	for _, preVote := range []bool{false, true} {
+		preVote := preVote
+
+		t.Run("PreVote="+strconv.FormatBool(preVote), func(t *testing.T) {
+			c := newClusterWithConfig(t, 1, func(config *Config) {
+				config.PreVote = preVote
+			})
+			defer c.stopAll()
+
+			time.Sleep(1 * time.Second)
+			leaderId, leaderTerm := c.checkSingleLeader()
+
+			// commands are committed by the log of the leader itself
+			numLogs := 10
+			logIds := make([]uint64, numLogs+1)
+			for i := 1; i <= numLogs; i++ {
+				ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+				resp, err := c.rafts[leaderId].ApplyCommand(ctx, &pb.ApplyCommandRequest{
+					Data: []byte("command " + strconv.Itoa(i)),
+					Wait: true,
+				})
+				cancel()
+				if err != nil {
+					t.Fatal("fail to apply command:", err)
+				}
+
+				logIds[i] = resp.GetEntry().GetId()
+				c.checkLog(leaderId, logIds[i], leaderTerm, []byte("command "+strconv.Itoa(i)))
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+			defer cancel()
+
+			resp, err := c.rafts[leaderId].ReadIndex(ctx, &pb.ReadIndexRequest{})
+			if err != nil {
+				t.Fatal("fail to read index:", err)
+			}
+			if resp.GetReadIndex() < logIds[numLogs] {
+				t.Fatalf("read index %d should be at least %d", resp.GetReadIndex(), logIds[numLogs])
+			}
+
+			// the restarted server becomes the leader again and applies its logs
+			c.stop(leaderId)
+			c.initialize(leaderId)
+			c.start(leaderId)
+
+			time.Sleep(1 * time.Second)
+			newLeaderId, newLeaderTerm := c.checkSingleLeader()
+			if newLeaderId != leaderId || newLeaderTerm <= leaderTerm {
+				t.Fatalf("server %d should be the leader in a higher term, got %d in term %d", leaderId, newLeaderId, newLeaderTerm)
+			}
+
+			for i := 1; i <= numLogs; i++ {
+				c.checkLog(leaderId, logIds[i], leaderTerm, []byte("command "+strconv.Itoa(i)))
+			}
+		})
 	}
 }
 
@@ -119,13 +229,14 @@ func TestManyLogsReplication(t *testing.T) {
 	leaderId, leaderTerm := c.checkSingleLeader()
 
 	numLogs := 2000
+	logIds := make([]uint64, numLogs+1)
 
 	var wg sync.WaitGroup
 	wg.Add(1)
 	go func() {
 		for i := 1; i <= numLogs; i++ {
 			data := []byte("command " + strconv.Itoa(i))
-			c.applyCommand(leaderId, leaderTerm, data)
+			logIds[i] = c.applyCommand(leaderId, leaderTerm, data)
 		}
 		wg.Done()
 	}()
@@ -137,7 +248,7 @@ func TestManyLogsReplication(t *testing.T) {
 		id := uint32(id)
 		for i := 1; i <= numLogs; i++ {
 			data := []byte("command " + strconv.Itoa(i))
-			c.checkLog(id, uint64(i), leaderTerm, data)
+			c.checkLog(id, logIds[i], leaderTerm, data)
 		}
 	}
 }
@@ -155,17 +266,23 @@ func TestLogReplicationWithFollowerFailure(t *testing.T) {
 	c.disconnectAll(peerId)
 
 	numLogs := 10
+	logIds := make(chan uint64, numLogs)
 
 	for i := 1; i <= numLogs; i++ {
 		data := []byte("command " + strconv.Itoa(i))
 
 		go func() {
-			c.applyCommand(leaderId, leaderTerm, data)
+			logIds <- c.applyCommand(leaderId, leaderTerm, data)
 		}()
 	}
 
 	time.Sleep(1 * time.Second)
 
+	ids := make([]uint64, 0, numLogs)
+	for i := 1; i <= numLogs; i++ {
+		ids = append(ids, <-logIds)
+	}
+
 	for id := 1; id <= numNodes; id++ {
 		id := uint32(id)
 
@@ -173,8 +290,8 @@ func TestLogReplicationWithFollowerFailure(t *testing.T) {
 			continue
 		}
 
-		for i := 1; i <= numLogs; i++ {
-			c.checkLog(id, uint64(i), leaderTerm, nil)
+		for _, logId := range ids {
+			c.checkLog(id, logId, leaderTerm, nil)
 		}
 	}
 
@@ -182,8 +299,8 @@ func TestLogReplicationWithFollowerFailure(t *testing.T) {
 	c.connectAll(peerId)
 
 	time.Sleep(1 * time.Second)
-	for i := 1; i <= numLogs; i++ {
-		c.checkLog(peerId, uint64(i), leaderTerm, nil)
+	for _, logId := range ids {
+		c.checkLog(peerId, logId, leaderTerm, nil)
 	}
 }
 
@@ -257,7 +374,7 @@ func TestOnlyUpToDateCandidateWinLeaderElection(t *testing.T) {
 
 	// log 1 are replicated on all followers
 	data1 := []byte("command 1")
-	c.applyCommand(oldLeaderId, oldLeaderTerm, data1)
+	log1Id := c.applyCommand(oldLeaderId, oldLeaderTerm, data1)
 	time.Sleep(500 * time.Millisecond)
 
 	peerId1 := randomPeerId(oldLeaderId, numNodes)
@@ -272,7 +389,7 @@ func TestOnlyUpToDateCandidateWinLeaderElection(t *testing.T) {
 
 	// log 2 are not replicated on peerId1 and peerId2
 	data2 := []byte("command 2")
-	c.applyCommand(oldLeaderId, oldLeaderTerm, data2)
+	log2Id := c.applyCommand(oldLeaderId, oldLeaderTerm, data2)
 
 	time.Sleep(1 * time.Second)
 	// leader failover
@@ -283,14 +400,14 @@ func TestOnlyUpToDateCandidateWinLeaderElection(t *testing.T) {
 	for i := 1; i <= numNodes; i++ {
 		id := uint32(i)
 
-		c.checkLog(id, 1, oldLeaderTerm, data1)
+		c.checkLog(id, log1Id, oldLeaderTerm, data1)
 
 		if id == peerId1 || id == peerId2 {
-			if l := c.consumers[id].getLog(2); l != nil {
+			if l := c.consumers[id].getLog(log2Id); l != nil {
 				t.Fatalf("node %d is already stopped, should not receive the log", id)
 			}
 		} else {
-			c.checkLog(id, 2, oldLeaderTerm, data2)
+			c.checkLog(id, log2Id, oldLeaderTerm, data2)
 		}
 	}
 
@@ -324,8 +441,8 @@ func TestOnlyUpToDateCandidateWinLeaderElection(t *testing.T) {
 	for i := 1; i <= numNodes; i++ {
 		id := uint32(i)
 		if id != oldLeaderId {
-			c.checkLog(id, 1, oldLeaderTerm, data1)
-			c.checkLog(id, 2, oldLeaderTerm, data2)
+			c.checkLog(id, log1Id, oldLeaderTerm, data1)
+			c.checkLog(id, log2Id, oldLeaderTerm, data2)
 		}
 	}
 
@@ -343,8 +460,8 @@ func TestOnlyUpToDateCandidateWinLeaderElection(t *testing.T) {
 	if leaderId != newLeaderId || leaderTerm != newLeaderTerm {
 		t.Fatalf("leader come back should not affect the current leader")
 	}
-	c.checkLog(oldLeaderId, 1, oldLeaderTerm, data1)
-	c.checkLog(oldLeaderId, 2, oldLeaderTerm, data2)
+	c.checkLog(oldLeaderId, log1Id, oldLeaderTerm, data1)
+	c.checkLog(oldLeaderId, log2Id, oldLeaderTerm, data2)
 }
 
 func TestCannotCommitLogIfTermMismatch(t *testing.T) {
@@ -359,16 +476,19 @@ func TestCannotCommitLogIfTermMismatch(t *testing.T) {
 
 	// log 1 are replicated on all followers
 	data1 := []byte("command 1")
-	c.applyCommand(oldLeaderId, oldLeaderTerm, data1)
+	log1Id := c.applyCommand(oldLeaderId, oldLeaderTerm, data1)
 	time.Sleep(500 * time.Millisecond)
 
 	// log 2 is replicated on no follower
 	c.disconnectAll(oldLeaderId)
+	for _, peerId := range c.peerIds(oldLeaderId) {
+		c.disconnect(peerId, oldLeaderId)
+	}
 	data2 := []byte("command 2")
 	log2Id := c.applyCommand(oldLeaderId, oldLeaderTerm, data2)
 
 	time.Sleep(1 * time.Second)
-	newLeaderId, newLeaderTerm := c.checkSingleLeader()
+	newLeaderId, newLeaderTerm := c.waitSingleLeader(2 * time.Second)
 	if newLeaderId == oldLeaderId {
 		t.Fatalf("invalid leader, node %d already stop", oldLeaderId)
 	}
@@ -378,12 +498,24 @@ func TestCannotCommitLogIfTermMismatch(t *testing.T) {
 
 	// old leader is back from the network partition
 	c.connectAll(oldLeaderId)
+	for _, peerId := range c.peerIds(oldLeaderId) {
+		c.connect(peerId, oldLeaderId)
+	}
 	time.Sleep(1 * time.Second)
 	leaderId, leaderTerm := c.checkSingleLeader()
 	if newLeaderId != leaderId || newLeaderTerm != leaderTerm {
 		t.Fatal("new leader should not be affected when the old leader com")
 	}
 
+	// log 2 is not committed, it is overwritten by the no-op of the new leader
+	oldLeader := c.rafts[oldLeaderId]
+	oldLeader.mu.Lock()
+	if log := oldLeader.getLog(log2Id); log == nil || log.GetTerm() != newLeaderTerm {
+		oldLeader.mu.Unlock()
+		t.Fatalf("log 2 should be overwritten by the new leader on server %d", oldLeaderId)
+	}
+	oldLeader.mu.Unlock()
+
 	// we disconnect all outgoing RPCs to all servers except the old leader
 	// thus forces the old leader to become the next leader
 	for i := 1; i <= numNodes; i++ {
@@ -394,7 +526,7 @@ func TestCannotCommitLogIfTermMismatch(t *testing.T) {
 	}
 
 	time.Sleep(1 * time.Second)
-	leaderId, leaderTerm = c.checkSingleLeader()
+	leaderId, leaderTerm = c.waitSingleLeader(5 * time.Second)
 	if leaderId != oldLeaderId {
 		t.Fatal("leader should go back to the old leader after our manually operation")
 	}
@@ -410,30 +542,843 @@ func TestCannotCommitLogIfTermMismatch(t *testing.T) {
 		}
 	}
 
-	// log 2 should be replicated to all servers now but cannot commit since the log does not match current term
+	// log 3 is replicated on all servers
+	// then all logs are committed
+	data3 := []byte("command 3")
+	log3Id := c.applyCommand(leaderId, leaderTerm, data3)
+
+	time.Sleep(500 * time.Millisecond)
+	for i := 1; i <= numNodes; i++ {
+		id := uint32(i)
+		c.checkLog(id, log1Id, oldLeaderTerm, data1)
+		c.checkLog(id, log3Id, leaderTerm, data3)
+
+		if c.consumers[id].getLog(log2Id) != nil {
+			t.Fatalf("log 2 is committed on server %d", id)
+		}
+	}
+}
+
+func TestNoOpCommitsPreviousTermLogs(t *testing.T) {
+	numNodes := 5
+
+	c := newClusterWithConfig(t, numNodes, func(config *Config) {
+		config.PreVote = true
+	})
+	defer c.stopAll()
+
+	time.Sleep(1 * time.Second)
+	oldLeaderId, oldLeaderTerm := c.checkSingleLeader()
+
+	// only a follower can receive logs from the leader, and other followers can not start elections
+	newLeaderId := randomPeerId(oldLeaderId, numNodes)
+	for i := 1; i <= numNodes; i++ {
+		id := uint32(i)
+		if id != oldLeaderId && id != newLeaderId {
+			c.disconnect(oldLeaderId, id)
+			c.disconnectAll(id)
+		}
+	}
+
+	// the log is replicated on 2 servers, it is not committed
+	data := []byte("command 1")
+	logId := c.applyCommand(oldLeaderId, oldLeaderTerm, data)
+	time.Sleep(100 * time.Millisecond)
+
+	// the follower with the log becomes the leader, and commits the log without new commands
+	c.stop(oldLeaderId)
+	time.Sleep(1 * time.Second)
+
+	leaderId, leaderTerm := c.getCurrentLeader()
+	if leaderId != newLeaderId || leaderTerm <= oldLeaderTerm {
+		t.Fatalf("expect server %d is the leader with term greater than %d, got %d with term %d",
+			newLeaderId, oldLeaderTerm, leaderId, leaderTerm)
+	}
+
+	for i := 1; i <= numNodes; i++ {
+		id := uint32(i)
+		if id != oldLeaderId {
+			c.checkLog(id, logId, oldLeaderTerm, data)
+		}
+	}
+}
+
+func TestFastLogBacktracking(t *testing.T) {
+	numNodes := 3
+
+	c := newCluster(t, numNodes)
+	defer c.stopAll()
+
+	time.Sleep(1 * time.Second)
+	oldLeaderId, oldLeaderTerm := c.checkSingleLeader()
+
+	// isolate the leader, logs appended to it are never committed
+	c.disconnectAll(oldLeaderId)
+	for _, peerId := range c.peerIds(oldLeaderId) {
+		c.disconnect(peerId, oldLeaderId)
+	}
+
+	numLogs := 50
+	for i := 1; i <= numLogs; i++ {
+		data := []byte("stale command " + strconv.Itoa(i))
+		c.applyCommand(oldLeaderId, oldLeaderTerm, data)
+	}
+
+	// the rest of the cluster elects a new leader and appends logs conflicting with the old leader
+	time.Sleep(1 * time.Second)
+	leaderId, leaderTerm := c.getCurrentLeader()
+	if leaderId == oldLeaderId {
+		t.Fatal("isolated leader should step down")
+	}
+
+	logIds := make([]uint64, numLogs+1)
+	for i := 1; i <= numLogs; i++ {
+		data := []byte("command " + strconv.Itoa(i))
+		logIds[i] = c.applyCommand(leaderId, leaderTerm, data)
+	}
+
+	// a leader elected after the logs are appended starts probing the old leader from the end of its logs
+	followerId := randomPeerId(leaderId, numNodes)
+	for followerId == oldLeaderId {
+		followerId = randomPeerId(leaderId, numNodes)
+	}
+	c.transferLeadership(leaderId, followerId)
+	time.Sleep(500 * time.Millisecond)
+
+	if newLeaderId, _ := c.getCurrentLeader(); newLeaderId != followerId {
+		t.Fatalf("leadership should be transferred to %d, got %d", followerId, newLeaderId)
+	}
+
+	rejections := c.getRejections(oldLeaderId)
+
+	c.connectAll(oldLeaderId)
+	for _, peerId := range c.peerIds(oldLeaderId) {
+		c.connect(peerId, oldLeaderId)
+	}
+
+	time.Sleep(1 * time.Second)
+
+	for i := 1; i <= numLogs; i++ {
+		data := []byte("command " + strconv.Itoa(i))
+		c.checkLog(oldLeaderId, logIds[i], leaderTerm, data)
+	}
+
+	// the old leader rejects once for the logs it misses and once for the conflicting term,
+	// the next index skips all conflicting logs of the term at once instead of decreasing one by one
+	if n := c.getRejections(oldLeaderId) - rejections; n > 2 {
+		t.Fatalf("old leader should reject at most twice, got %d rejections", n)
+	}
+}
+
+func TestPipelinedReplication(t *testing.T) {
+	numNodes := 3
+
+	c := newClusterWithConfig(t, numNodes, func(config *Config) {
+		config.MaxInflightAppendEntries = 2
+		config.MaxAppendEntries = 4
+		// the isolated follower should not disrupt the leader while commands are applied
+		config.PreVote = true
+	})
+	defer c.stopAll()
+
+	time.Sleep(1 * time.Second)
+	leaderId, leaderTerm := c.checkSingleLeader()
+
+	// isolate a follower from the leader
+	peerId := randomPeerId(leaderId, numNodes)
+	c.disconnect(leaderId, peerId)
+
+	numLogs := 400
+	logIds := make([]uint64, numLogs+1)
+	for i := 1; i <= numLogs; i++ {
+		data := []byte("command " + strconv.Itoa(i))
+		logIds[i] = c.applyCommand(leaderId, leaderTerm, data)
+	}
+
+	// the follower comes back, 100 batches of logs should be caught up without waiting for heartbeats
+	c.connect(leaderId, peerId)
+
+	time.Sleep(500 * time.Millisecond)
+
+	for i := 1; i <= numLogs; i++ {
+		data := []byte("command " + strconv.Itoa(i))
+		c.checkLog(peerId, logIds[i], leaderTerm, data)
+	}
+}
+
+func TestImmediateReplication(t *testing.T) {
+	numNodes := 3
+
+	c := newClusterWithConfig(t, numNodes, func(config *Config) {
+		config.HeartbeatInterval = 100 * time.Millisecond
+		config.HeartbeatTimeout = 300 * time.Millisecond
+		config.ElectionTimeout = 300 * time.Millisecond
+	})
+	defer c.stopAll()
+
+	time.Sleep(2 * time.Second)
+	leaderId, leaderTerm := c.checkSingleLeader()
+
+	// commands are committed without waiting for heartbeats
+	numLogs := 20
+	start := time.Now()
+
+	var resp *pb.ApplyCommandResponse
+	for i := 1; i <= numLogs; i++ {
+		data := []byte("command " + strconv.Itoa(i))
+
+		var err error
+		resp, err = c.rafts[leaderId].ApplyCommand(context.Background(), &pb.ApplyCommandRequest{Data: data, Wait: true})
+		if err != nil {
+			t.Fatal("fail to apply command:", err)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Duration(numLogs)*100*time.Millisecond/2 {
+		t.Fatalf("%d commands should be committed faster than heartbeats, took %v", numLogs, elapsed)
+	}
+
+	c.checkLog(leaderId, resp.GetEntry().GetId(), leaderTerm, []byte("command "+strconv.Itoa(numLogs)))
+}
+
+func TestSlowFollowerReplication(t *testing.T) {
+	for _, disablePipeline := range []bool{false, true} {
+		disablePipeline := disablePipeline
+
+		t.Run("DisablePipeline="+strconv.FormatBool(disablePipeline), func(t *testing.T) {
+			numNodes := 3
+
+			c := newClusterWithConfig(t, numNodes, func(config *Config) {
+				config.PreVote = true
+				config.DisablePipeline = disablePipeline
+			})
+			defer c.stopAll()
+
+			time.Sleep(1 * time.Second)
+			leaderId, leaderTerm := c.checkSingleLeader()
+
+			// requests to the slow follower block until it is released
+			slowId := randomPeerId(leaderId, numNodes)
+			c.block(slowId)
+
+			goroutines := runtime.NumGoroutine()
+
+			numLogs := 100
+			logIds := make([]uint64, numLogs+1)
+			for i := 1; i <= numLogs; i++ {
+				data := []byte("command " + strconv.Itoa(i))
+				logIds[i] = c.applyCommand(leaderId, leaderTerm, data)
+			}
+
+			time.Sleep(1 * time.Second)
+
+			// heartbeats and new logs should not pile up goroutines for the slow follower
+			if n := runtime.NumGoroutine(); n > goroutines+10 {
+				t.Fatalf("expect goroutines are bounded, got %d goroutines, %d before", n, goroutines)
+			}
+
+			// the other follower is not slowed down
+			for id := 1; id <= numNodes; id++ {
+				id := uint32(id)
+				if id == slowId {
+					continue
+				}
+
+				for i := 1; i <= numLogs; i++ {
+					data := []byte("command " + strconv.Itoa(i))
+					c.checkLog(id, logIds[i], leaderTerm, data)
+				}
+			}
+
+			c.unblock(slowId)
+			time.Sleep(1 * time.Second)
+
+			for i := 1; i <= numLogs; i++ {
+				data := []byte("command " + strconv.Itoa(i))
+				c.checkLog(slowId, logIds[i], leaderTerm, data)
+			}
+		})
+	}
+}
+
+func TestAddAndRemoveServer(t *testing.T) {
+	numNodes := 3
+
+	c := newCluster(t, numNodes)
+	defer c.stopAll()
+
+	time.Sleep(1 * time.Second)
+	leaderId, leaderTerm := c.checkSingleLeader()
+
+	// the leader must commit a log in its term before changing the configuration
+	data1 := []byte("command 1")
+	log1Id := c.applyCommand(leaderId, leaderTerm, data1)
+	time.Sleep(500 * time.Millisecond)
+
+	// add a new server, logs should be replicated to the new server
+	newId := uint32(numNodes + 1)
+	c.join(newId)
+	c.addServer(leaderId, newId, pb.Suffrage_VOTER)
+
+	time.Sleep(1 * time.Second)
+	c.checkLog(newId, log1Id, leaderTerm, data1)
+
 	for id, raft := range c.rafts {
 		raft.mu.Lock()
-		if raft.getLog(log2Id) == nil {
-			t.Fatalf("log 2 does not replicated on server %d", raft.id)
+		if n := len(raft.configuration.GetServers()); n != numNodes+1 {
+			t.Fatalf("server %d should have %d servers in the configuration, got %d", id, numNodes+1, n)
 		}
 		raft.mu.Unlock()
+	}
 
-		if c.consumers[id].getLog(log2Id) != nil {
-			t.Fatalf("log 2 is committed on server %d", raft.id)
+	// remove the leader, a new leader should be elected from the remaining servers
+	c.removeServer(leaderId, leaderId)
+
+	time.Sleep(1 * time.Second)
+	newLeaderId, newLeaderTerm := c.checkSingleLeader()
+	if newLeaderId == leaderId {
+		t.Fatalf("server %d is removed, should not be the leader", leaderId)
+	}
+
+	data2 := []byte("command 2")
+	logId := c.applyCommand(newLeaderId, newLeaderTerm, data2)
+
+	time.Sleep(500 * time.Millisecond)
+	for id := range c.rafts {
+		if id != leaderId {
+			c.checkLog(id, logId, newLeaderTerm, data2)
 		}
 	}
+}
 
-	// log 3 is replicated on all servers
-	// then all logs are committed
-	data3 := []byte("command 3")
-	c.applyCommand(leaderId, leaderTerm, data3)
+func TestLearnerPromotion(t *testing.T) {
+	numNodes := 3
+
+	c := newCluster(t, numNodes)
+	defer c.stopAll()
+
+	time.Sleep(1 * time.Second)
+	leaderId, leaderTerm := c.checkSingleLeader()
 
+	data1 := []byte("command 1")
+	log1Id := c.applyCommand(leaderId, leaderTerm, data1)
 	time.Sleep(500 * time.Millisecond)
+
+	// add a learner, logs should be replicated to the learner
+	learnerId := uint32(numNodes + 1)
+	c.join(learnerId)
+	c.addServer(leaderId, learnerId, pb.Suffrage_LEARNER)
+
+	time.Sleep(1 * time.Second)
+	c.checkLog(learnerId, log1Id, leaderTerm, data1)
+
+	// learners do not count toward the quorum
+	leader := c.rafts[leaderId]
+	leader.mu.Lock()
+	if leader.isVoter(learnerId) {
+		t.Fatalf("server %d should be a learner", learnerId)
+	}
+	if n := leader.quorumSize(); n != numNodes/2+1 {
+		t.Fatalf("quorum size should be %d, got %d", numNodes/2+1, n)
+	}
+	leader.mu.Unlock()
+
+	// the learner has caught up, promote it to a voter
+	c.promoteLearner(leaderId, learnerId)
+
+	time.Sleep(500 * time.Millisecond)
+	for id, raft := range c.rafts {
+		raft.mu.Lock()
+		if !raft.isVoter(learnerId) {
+			t.Fatalf("server %d should see server %d as a voter", id, learnerId)
+		}
+		raft.mu.Unlock()
+	}
+}
+
+func TestSnapshotAndInstallSnapshot(t *testing.T) {
+	numNodes := 3
+
+	c := newCluster(t, numNodes)
+	defer c.stopAll()
+
+	time.Sleep(1 * time.Second)
+	leaderId, leaderTerm := c.checkSingleLeader()
+
+	// isolate a follower from the leader
+	peerId := randomPeerId(leaderId, numNodes)
+	c.disconnectAll(peerId)
+	c.disconnect(leaderId, peerId)
+
+	numLogs := 10
+	logIds := make([]uint64, 2*numLogs+1)
+	for i := 1; i <= numLogs; i++ {
+		data := []byte("command " + strconv.Itoa(i))
+		logIds[i] = c.applyCommand(leaderId, leaderTerm, data)
+	}
+
+	time.Sleep(500 * time.Millisecond)
+
+	// compact logs on all servers except the isolated follower
 	for i := 1; i <= numNodes; i++ {
 		id := uint32(i)
-		c.checkLog(id, 1, oldLeaderTerm, data1)
-		c.checkLog(id, 2, oldLeaderTerm, data2)
-		c.checkLog(id, 3, leaderTerm, data3)
+		if id != peerId {
+			c.snapshot(id, logIds[numLogs])
+		}
+	}
+
+	for i := numLogs + 1; i <= 2*numLogs; i++ {
+		data := []byte("command " + strconv.Itoa(i))
+		logIds[i] = c.applyCommand(leaderId, leaderTerm, data)
+	}
+
+	time.Sleep(500 * time.Millisecond)
+
+	// the follower comes back, logs should be caught up by the snapshot and the remaining logs
+	c.connectAll(peerId)
+	c.connect(leaderId, peerId)
+
+	time.Sleep(2 * time.Second)
+
+	for i := 1; i <= 2*numLogs; i++ {
+		data := []byte("command " + strconv.Itoa(i))
+		c.checkLog(peerId, logIds[i], leaderTerm, data)
+	}
+
+	raft := c.rafts[peerId]
+	raft.mu.Lock()
+	if raft.lastIncludedId != logIds[numLogs] {
+		t.Fatalf("follower should install the snapshot at log %d, got %d", logIds[numLogs], raft.lastIncludedId)
+	}
+	raft.mu.Unlock()
+}
+
+func TestLeadershipTransfer(t *testing.T) {
+	numNodes := 5
+
+	c := newCluster(t, numNodes)
+	defer c.stopAll()
+
+	time.Sleep(1 * time.Second)
+	leaderId, leaderTerm := c.checkSingleLeader()
+
+	data1 := []byte("command 1")
+	log1Id := c.applyCommand(leaderId, leaderTerm, data1)
+
+	// TimeoutNow is ignored unless it is sent by the current leader
+	targetId := randomPeerId(leaderId, numNodes)
+	req := &pb.TimeoutNowRequest{Term: leaderTerm, LeaderId: randomPeerId(targetId, numNodes)}
+	for req.GetLeaderId() == leaderId {
+		req.LeaderId = randomPeerId(targetId, numNodes)
+	}
+	if _, err := c.rafts[targetId].TimeoutNow(context.Background(), req); err != nil {
+		t.Fatal("fail to send TimeoutNow:", err)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	if id, term := c.checkSingleLeader(); id != leaderId || term != leaderTerm {
+		t.Fatalf("TimeoutNow not sent by the leader should not start an election, got leader %d at term %d", id, term)
+	}
+
+	// transfer the leadership to a random follower, it should become the leader immediately
+	c.transferLeadership(leaderId, targetId)
+
+	time.Sleep(200 * time.Millisecond)
+	newLeaderId, newLeaderTerm := c.checkSingleLeader()
+	if newLeaderId != targetId {
+		t.Fatalf("leadership should be transferred to server %d, got %d", targetId, newLeaderId)
+	}
+
+	data2 := []byte("command 2")
+	id := c.applyCommand(newLeaderId, newLeaderTerm, data2)
+
+	time.Sleep(500 * time.Millisecond)
+	for i := 1; i <= numNodes; i++ {
+		c.checkLog(uint32(i), log1Id, leaderTerm, data1)
+		c.checkLog(uint32(i), id, newLeaderTerm, data2)
+	}
+}
+
+func TestPreVoteWithPartitionedFollower(t *testing.T) {
+	numNodes := 3
+
+	c := newClusterWithConfig(t, numNodes, func(config *Config) {
+		config.PreVote = true
+	})
+	defer c.stopAll()
+
+	time.Sleep(1 * time.Second)
+	leaderId, leaderTerm := c.checkSingleLeader()
+
+	// isolate a follower, it should not increase its term since it can not win the pre-vote
+	peerId := randomPeerId(leaderId, numNodes)
+	c.disconnectAll(peerId)
+	for id := range c.rafts {
+		if id != peerId {
+			c.disconnect(id, peerId)
+		}
+	}
+
+	time.Sleep(1 * time.Second)
+
+	raft := c.rafts[peerId]
+	raft.mu.Lock()
+	if raft.currentTerm != leaderTerm {
+		t.Fatalf("isolated follower should not increase its term %d, got %d", leaderTerm, raft.currentTerm)
+	}
+	raft.mu.Unlock()
+
+	// the follower comes back, the leader should not be disrupted
+	c.connectAll(peerId)
+	for id := range c.rafts {
+		if id != peerId {
+			c.connect(id, peerId)
+		}
+	}
+
+	time.Sleep(500 * time.Millisecond)
+	newLeaderId, newLeaderTerm := c.checkSingleLeader()
+	if newLeaderId != leaderId || newLeaderTerm != leaderTerm {
+		t.Fatalf("leader should not be disrupted, expect server %d in term %d, got server %d in term %d",
+			leaderId, leaderTerm, newLeaderId, newLeaderTerm)
+	}
+}
+
+func TestLeaderStepDownWithoutQuorum(t *testing.T) {
+	numNodes := 3
+
+	c := newCluster(t, numNodes)
+	defer c.stopAll()
+
+	time.Sleep(1 * time.Second)
+	leaderId, leaderTerm := c.checkSingleLeader()
+
+	// isolate the leader, it should step down since it can not contact a quorum
+	c.disconnectAll(leaderId)
+	for id := range c.rafts {
+		if id != leaderId {
+			c.disconnect(id, leaderId)
+		}
+	}
+
+	time.Sleep(1 * time.Second)
+
+	leader := c.rafts[leaderId]
+	leader.mu.Lock()
+	if leader.state == Leader {
+		t.Fatalf("isolated leader %d should step down", leaderId)
+	}
+	// the stepped-down leader runs the pre-vote even though PreVote is disabled, so its term is not increased
+	if leader.config.PreVote || leader.currentTerm != leaderTerm {
+		t.Fatalf("isolated leader %d should stay in term %d without PreVote, got %d", leaderId, leaderTerm, leader.currentTerm)
+	}
+	leader.mu.Unlock()
+
+	if _, err := leader.ApplyCommand(context.Background(), &pb.ApplyCommandRequest{Data: []byte("command")}); !errors.Is(err, errNotLeader) {
+		t.Fatalf("isolated leader should reject commands, got %v", err)
+	}
+}
+
+func TestReadIndex(t *testing.T) {
+	numNodes := 3
+
+	c := newCluster(t, numNodes)
+	defer c.stopAll()
+
+	time.Sleep(1 * time.Second)
+	leaderId, leaderTerm := c.checkSingleLeader()
+
+	data1 := []byte("command 1")
+	id := c.applyCommand(leaderId, leaderTerm, data1)
+	time.Sleep(500 * time.Millisecond)
+
+	// logs committed before the read should be applied once ReadIndex returns
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	resp, err := c.rafts[leaderId].ReadIndex(ctx, &pb.ReadIndexRequest{})
+	if err != nil {
+		t.Fatal("fail to read index:", err)
+	}
+	if resp.GetReadIndex() < id {
+		t.Fatalf("read index %d should be at least %d", resp.GetReadIndex(), id)
+	}
+	c.checkLog(leaderId, id, leaderTerm, data1)
+
+	// followers can not serve reads
+	peerId := randomPeerId(leaderId, numNodes)
+	if _, err := c.rafts[peerId].ReadIndex(ctx, &pb.ReadIndexRequest{}); !errors.Is(err, errNotLeader) {
+		t.Fatalf("follower should reject reads, got %v", err)
+	}
+
+	// an isolated leader can not confirm its leadership
+	c.disconnectAll(leaderId)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if _, err := c.rafts[leaderId].ReadIndex(ctx, &pb.ReadIndexRequest{}); err == nil {
+		t.Fatal("isolated leader should not serve reads")
+	}
+}
+
+func TestLeaseRead(t *testing.T) {
+	numNodes := 3
+
+	c := newClusterWithConfig(t, numNodes, func(config *Config) {
+		config.ElectionTimeout = 300 * time.Millisecond
+		config.LeaseDriftMargin = 20 * time.Millisecond
+	})
+	defer c.stopAll()
+
+	time.Sleep(1 * time.Second)
+	leaderId, leaderTerm := c.checkSingleLeader()
+
+	data1 := []byte("command 1")
+	id := c.applyCommand(leaderId, leaderTerm, data1)
+	time.Sleep(500 * time.Millisecond)
+
+	// the leader holds the lease by heartbeats, reads are served from the lease
+	leader := c.rafts[leaderId]
+	if !leader.LeaseState().Valid() {
+		t.Fatal("leader should hold a valid lease")
+	}
+
+	// followers may start an election once the heartbeat timeout passes, which is shorter than the election timeout
+	if d := time.Until(leader.LeaseState().Expiry); d > leader.config.HeartbeatTimeout-leader.config.LeaseDriftMargin {
+		t.Fatalf("lease should not outlast the heartbeat timeout, got %v", d)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	resp, err := leader.LeaseRead(ctx, &pb.LeaseReadRequest{})
+	if err != nil {
+		t.Fatal("fail to lease read:", err)
+	}
+	if !resp.GetLease() {
+		t.Fatal("read should be served from the lease")
+	}
+	if resp.GetReadIndex() < id {
+		t.Fatalf("read index %d should be at least %d", resp.GetReadIndex(), id)
+	}
+	if n := leader.LeaseState().LeaseReads; n != 1 {
+		t.Fatalf("number of lease reads should be 1, got %d", n)
+	}
+
+	// the lease expires once the leader is isolated
+	c.disconnectAll(leaderId)
+	time.Sleep(200 * time.Millisecond)
+
+	if leader.LeaseState().Valid() {
+		t.Fatal("isolated leader should not hold a valid lease")
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if _, err := leader.LeaseRead(ctx, &pb.LeaseReadRequest{}); err == nil {
+		t.Fatal("isolated leader should not serve reads")
+	}
+}
+
+func TestApplyCommandAndWait(t *testing.T) {
+	numNodes := 3
+
+	c := newCluster(t, numNodes)
+	defer c.stopAll()
+
+	time.Sleep(1 * time.Second)
+	leaderId, leaderTerm := c.checkSingleLeader()
+
+	// the command is committed and applied once ApplyCommand returns
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	data1 := []byte("command 1")
+	resp, err := c.rafts[leaderId].ApplyCommand(ctx, &pb.ApplyCommandRequest{Data: data1, Wait: true})
+	if err != nil {
+		t.Fatal("fail to apply command:", err)
+	}
+
+	id := resp.GetEntry().GetId()
+	c.checkLog(leaderId, id, leaderTerm, data1)
+
+	// only an FSM responds results, commands consumed from ApplyCh are responded with an empty result
+	if result := resp.GetResult(); len(result) != 0 {
+		t.Fatalf("result of the command consumed from ApplyCh should be empty, got %q", result)
+	}
+
+	// the isolated leader steps down before the command is committed
+	c.disconnectAll(leaderId)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	data2 := []byte("command 2")
+	if _, err := c.rafts[leaderId].ApplyCommand(ctx, &pb.ApplyCommandRequest{Data: data2, Wait: true}); err != errLeadershipLost {
+		t.Fatalf("command should fail since leadership is lost, got %v", err)
+	}
+}
+
+func TestClientSession(t *testing.T) {
+	numNodes := 3
+
+	c := newClusterWithConfig(t, numNodes, func(config *Config) {
+		config.SessionTimeout = 500 * time.Millisecond
+	})
+	defer c.stopAll()
+
+	time.Sleep(1 * time.Second)
+	leaderId, _ := c.checkSingleLeader()
+	leader := c.rafts[leaderId]
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	req := &pb.ApplyCommandRequest{Data: []byte("command 1"), Wait: true, ClientId: 1, Sequence: 1}
+	resp, err := leader.ApplyCommand(ctx, req)
+	if err != nil {
+		t.Fatal("fail to apply command:", err)
+	}
+
+	// the retried command is responded with the cached result
+	retryResp, err := leader.ApplyCommand(ctx, req)
+	if err != nil {
+		t.Fatal("fail to retry command:", err)
+	}
+	if retryResp.GetEntry() != nil {
+		t.Fatal("retried command should not be appended again")
+	}
+	if string(retryResp.GetResult()) != string(resp.GetResult()) {
+		t.Fatalf("retried command should be responded with the cached result %q, got %q", resp.GetResult(), retryResp.GetResult())
+	}
+
+	// the duplicate command appended before the first one is applied is not applied again
+	req = &pb.ApplyCommandRequest{Data: []byte("command 2"), ClientId: 1, Sequence: 2}
+	resp1, err := leader.ApplyCommand(ctx, req)
+	if err != nil {
+		t.Fatal("fail to apply command:", err)
+	}
+	resp2, err := leader.ApplyCommand(ctx, req)
+	if err != nil {
+		t.Fatal("fail to apply command:", err)
+	}
+
+	time.Sleep(500 * time.Millisecond)
+	for id, consumer := range c.consumers {
+		if consumer.getLog(resp1.GetEntry().GetId()) == nil {
+			t.Fatalf("command is not applied on server %d", id)
+		}
+		if consumer.getLog(resp2.GetEntry().GetId()) != nil {
+			t.Fatalf("duplicate command is applied on server %d", id)
+		}
+	}
+
+	req = &pb.ApplyCommandRequest{Data: []byte("command 1"), ClientId: 1, Sequence: 1}
+	if _, err := leader.ApplyCommand(ctx, req); err != errSequenceOutdated {
+		t.Fatalf("outdated command should be rejected, got %v", err)
+	}
+
+	// the inactive session is expired on all servers
+	time.Sleep(1500 * time.Millisecond)
+	for id, raft := range c.rafts {
+		if raft.getSession(1) != nil {
+			t.Fatalf("session should be expired on server %d", id)
+		}
+	}
+
+	// the client of the expired session must open a new one instead of continuing the sequence
+	ctx, cancel = context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	req = &pb.ApplyCommandRequest{Data: []byte("command 3"), Wait: true, ClientId: 1, Sequence: 3}
+	if _, err := leader.ApplyCommand(ctx, req); err != errSessionExpired {
+		t.Fatalf("command of the expired session should be rejected, got %v", err)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	for id, raft := range c.rafts {
+		if raft.getSession(1) != nil {
+			t.Fatalf("command of the expired session should not open a session on server %d", id)
+		}
+	}
+
+	req = &pb.ApplyCommandRequest{Data: []byte("command 1"), Wait: true, ClientId: 1, Sequence: 1}
+	if _, err := leader.ApplyCommand(ctx, req); err != nil {
+		t.Fatal("fail to open a new session:", err)
+	}
+}
+
+func TestApplyCommandForwarding(t *testing.T) {
+	numNodes := 3
+
+	c := newClusterWithConfig(t, numNodes, func(config *Config) {
+		config.ForwardApplyCommand = true
+	})
+	defer c.stopAll()
+
+	time.Sleep(1 * time.Second)
+	leaderId, leaderTerm := c.checkSingleLeader()
+
+	// the command is forwarded from a follower to the leader
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	peerId := randomPeerId(leaderId, numNodes)
+	data1 := []byte("command 1")
+	resp, err := c.rafts[peerId].ApplyCommand(ctx, &pb.ApplyCommandRequest{Data: data1, Wait: true})
+	if err != nil {
+		t.Fatal("fail to forward command:", err)
+	}
+
+	id := resp.GetEntry().GetId()
+
+	time.Sleep(500 * time.Millisecond)
+	for i := 1; i <= numNodes; i++ {
+		c.checkLog(uint32(i), id, leaderTerm, data1)
+	}
+}
+
+func TestApplyCommandLeaderHint(t *testing.T) {
+	numNodes := 3
+
+	c := newCluster(t, numNodes)
+	defer c.stopAll()
+
+	time.Sleep(1 * time.Second)
+	leaderId, _ := c.checkSingleLeader()
+
+	// the follower rejects the command with the hint of the leader
+	peerId := randomPeerId(leaderId, numNodes)
+	_, err := c.rafts[peerId].ApplyCommand(context.Background(), &pb.ApplyCommandRequest{Data: []byte("command 1")})
+
+	var notLeaderErr *NotLeaderError
+	if !errors.As(err, &notLeaderErr) {
+		t.Fatalf("follower should reject the command with the leader hint, got %v", err)
+	}
+	if notLeaderErr.LeaderId != leaderId {
+		t.Fatalf("leader hint should be %d, got %d", leaderId, notLeaderErr.LeaderId)
+	}
+
+	leaderAddr := c.listerers[leaderId].Addr().String()
+	if notLeaderErr.LeaderAddress != leaderAddr {
+		t.Fatalf("leader hint should be at %q, got %q", leaderAddr, notLeaderErr.LeaderAddress)
+	}
+
+	// gRPC clients receive the status code with the leader hint
+	_, err = c.peer(leaderId, peerId).ApplyCommand(context.Background(), &pb.ApplyCommandRequest{Data: []byte("command 1")})
+	if code := status.Code(err); code != codes.FailedPrecondition {
+		t.Fatalf("status code should be %s, got %s", codes.FailedPrecondition, code)
+	}
+
+	hint, ok := LeaderHintFromError(err)
+	if !ok {
+		t.Fatal("status should have the leader hint")
+	}
+	if hint.GetLeaderId() != leaderId || hint.GetLeaderAddress() != leaderAddr || hint.GetTerm() != notLeaderErr.Term {
+		t.Fatalf("leader hint should be %d at %q in term %d, got %d at %q in term %d",
+			leaderId, leaderAddr, notLeaderErr.Term, hint.GetLeaderId(), hint.GetLeaderAddress(), hint.GetTerm())
 	}
 }
 
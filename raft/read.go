@@ -0,0 +1,97 @@
+package raft
+
+import (
+	"github.com/justin0u0/raft/pb"
+	"go.uber.org/zap"
+)
+
+// pendingRead is a read waiting for the leader to confirm its leadership
+type pendingRead struct {
+	rpc       *rpc
+	readIndex uint64
+	// round is the first heartbeat round sent after the read is received,
+	// the leadership is confirmed once a quorum of voters respond to this round or a later one
+	round uint64
+	// lease is true if the read falls back from a lease read
+	lease bool
+}
+
+// RPC handlers
+
+// readIndex records the commit index as the read index, the rpc is responded after the leadership is confirmed
+func (r *Raft) readIndex(rpc *rpc, req *pb.ReadIndexRequest) {
+	if err := r.checkRead(); err != nil {
+		rpc.respond(nil, err)
+		return
+	}
+
+	r.addPendingRead(rpc, false)
+}
+
+// leader related
+
+// checkRead checks if the leader can serve reads
+func (r *Raft) checkRead() error {
+	if r.state != Leader {
+		return r.notLeaderError()
+	}
+
+	// the leader may not know the latest commit index until it commits a log in its term
+	if term, _ := r.getLogTerm(r.commitIndex); term != r.currentTerm {
+		return errLeaderNotCommitted
+	}
+
+	return nil
+}
+
+func (r *Raft) addPendingRead(rpc *rpc, lease bool) {
+	r.pendingReads = append(r.pendingReads, &pendingRead{
+		rpc:       rpc,
+		readIndex: r.commitIndex,
+		round:     r.round + 1,
+		lease:     lease,
+	})
+
+	r.confirmReads()
+}
+
+// confirmReads responds pending reads whose leadership is confirmed by a quorum of voters
+func (r *Raft) confirmReads() {
+	for len(r.pendingReads) != 0 {
+		read := r.pendingReads[0]
+
+		acks := 0
+		for _, server := range r.configuration.GetServers() {
+			if server.GetSuffrage() != pb.Suffrage_VOTER {
+				continue
+			}
+
+			if server.GetId() == r.id || r.ackedRounds[server.GetId()] >= read.round {
+				acks++
+			}
+		}
+
+		// reads are ordered by rounds, later reads can not be confirmed either
+		if acks < r.quorumSize() {
+			return
+		}
+
+		if read.lease {
+			read.rpc.respond(&pb.LeaseReadResponse{ReadIndex: read.readIndex}, nil)
+		} else {
+			read.rpc.respond(&pb.ReadIndexResponse{ReadIndex: read.readIndex}, nil)
+		}
+		r.pendingReads = r.pendingReads[1:]
+
+		r.logger.Debug("confirm leadership for read", zap.Uint64("readIndex", read.readIndex))
+	}
+}
+
+// abortReads fails all pending reads after the leader steps down
+func (r *Raft) abortReads() {
+	for _, read := range r.pendingReads {
+		read.rpc.respond(nil, r.notLeaderError())
+	}
+
+	r.pendingReads = nil
+}
@@ -0,0 +1,256 @@
+package raft
+
+import (
+	"context"
+	"time"
+
+	"github.com/justin0u0/raft/pb"
+	"go.uber.org/zap"
+)
+
+// progress is the replication progress of a peer, only used by the leader
+type progress struct {
+	// probe is true if the leader does not know where the logs of the peer match `nextIndex`,
+	// only one AppendEntries with entries is sent at a time until the peer accepts one,
+	// otherwise entries are sent optimistically after the last sent log
+	probe bool
+	// inflights are the last log ids of AppendEntries with entries in flight, in the sending order
+	inflights []uint64
+	// snapshotting is true if an InstallSnapshot is in flight
+	snapshotting bool
+}
+
+// paused reports if no more entries can be sent until some inflight AppendEntries are responded
+func (p *progress) paused(maxInflights int) bool {
+	if p.probe {
+		return len(p.inflights) != 0
+	}
+
+	return len(p.inflights) >= maxInflights
+}
+
+// becomeProbe stops sending entries optimistically, and forgets AppendEntries in flight
+func (p *progress) becomeProbe() {
+	p.probe = true
+	p.inflights = nil
+}
+
+// ack frees AppendEntries in flight with entries up to and including the log id
+func (p *progress) ack(id uint64) {
+	i := 0
+	for i < len(p.inflights) && p.inflights[i] <= id {
+		i++
+	}
+
+	p.inflights = p.inflights[i:]
+}
+
+// resetProgress starts probing the peer from the next index
+func (r *Raft) resetProgress(peerId uint32, nextIndex uint64, matchIndex uint64) {
+	r.setNextAndMatchIndex(peerId, nextIndex, matchIndex)
+	r.progress[peerId] = &progress{probe: true}
+}
+
+func (r *Raft) getProgress(peerId uint32) *progress {
+	pr, ok := r.progress[peerId]
+	if !ok {
+		pr = &progress{probe: true}
+		r.progress[peerId] = pr
+	}
+
+	return pr
+}
+
+// notifyReplicate notifies the leader to replicate new logs without waiting for the next heartbeat
+func (r *Raft) notifyReplicate() {
+	select {
+	case r.replicateCh <- struct{}{}:
+	default:
+	}
+}
+
+type appendEntriesResult struct {
+	*pb.AppendEntriesResponse
+	req    *pb.AppendEntriesRequest
+	peerId uint32
+	// round is the heartbeat round carried by the request, zero if the request is not sent by a heartbeat
+	round  uint64
+	sentAt time.Time
+	// err is the error of the RPC, the response is nil if it is set
+	err error
+}
+
+func (r *Raft) broadcastAppendEntries(ctx context.Context, appendEntriesResultCh chan *appendEntriesResult, installSnapshotResultCh chan *installSnapshotResult) {
+	r.logger.Info("broadcast append entries")
+
+	r.round++
+	round := r.round
+	sentAt := time.Now()
+
+	for peerId, peer := range r.getPeers() {
+		r.replicate(ctx, peerId, peer, round, sentAt, appendEntriesResultCh, installSnapshotResultCh)
+	}
+}
+
+// replicate sends entries after the last sent log to the peer until the inflight window is full,
+// a heartbeat is sent instead if the round is not zero and no entries can be sent
+func (r *Raft) replicate(
+	ctx context.Context,
+	peerId uint32,
+	peer Peer,
+	round uint64,
+	sentAt time.Time,
+	appendEntriesResultCh chan *appendEntriesResult,
+	installSnapshotResultCh chan *installSnapshotResult,
+) {
+	nextIndex := r.nextIndex[peerId]
+	pr := r.getProgress(peerId)
+	rp := r.getReplicator(ctx, peerId, peer, appendEntriesResultCh, installSnapshotResultCh)
+
+	// logs needed by the follower are already compacted, send the snapshot instead
+	if nextIndex <= r.lastIncludedId {
+		if round != 0 && !pr.snapshotting {
+			pr.snapshotting = r.sendInstallSnapshot(rp)
+		}
+		return
+	}
+
+	var entries []*pb.Entry
+	if !pr.paused(r.config.maxInflightAppendEntries()) {
+		entries = r.getLogsLimited(nextIndex, r.config.maxAppendEntries(), r.config.maxAppendEntriesSize())
+	}
+
+	prevLogId := nextIndex - 1
+
+	if len(entries) == 0 {
+		if round == 0 {
+			return
+		}
+
+		// entries in flight may not be appended yet, the heartbeat only carries the log known to match
+		if len(pr.inflights) != 0 {
+			prevLogId = r.matchIndex[peerId]
+		}
+	}
+
+	prevLogTerm, _ := r.getLogTerm(prevLogId)
+
+	req := &pb.AppendEntriesRequest{
+		Term:           r.currentTerm,
+		LeaderId:       r.id,
+		LeaderCommitId: r.commitIndex,
+		PrevLogId:      prevLogId,
+		PrevLogTerm:    prevLogTerm,
+		Entries:        entries,
+	}
+
+	// the request is dropped if the replicator falls behind, entries are sent again after it catches up
+	if !rp.enqueue(&replicationRequest{appendEntries: req, round: round, sentAt: sentAt}) || len(entries) == 0 {
+		return
+	}
+
+	lastLogId := entries[len(entries)-1].GetId()
+	pr.inflights = append(pr.inflights, lastLogId)
+
+	// the next entries are sent without waiting for the response unless probing
+	if !pr.probe {
+		r.setNextAndMatchIndex(peerId, lastLogId+1, r.matchIndex[peerId])
+	}
+}
+
+func (r *Raft) handleAppendEntriesResult(result *appendEntriesResult) {
+	peerId := result.peerId
+	logger := r.logger.With(zap.Uint32("peer", peerId))
+
+	pr := r.getProgress(peerId)
+	prevLogId := result.req.GetPrevLogId()
+	entries := result.req.GetEntries()
+
+	if result.err != nil {
+		// connection issue, entries in flight are sent again from the last matched log
+		if len(entries) != 0 {
+			if !pr.probe {
+				r.setNextAndMatchIndex(peerId, r.matchIndex[peerId]+1, r.matchIndex[peerId])
+			}
+			pr.becomeProbe()
+		}
+
+		return
+	}
+
+	if result.GetTerm() > r.currentTerm {
+		r.toFollower(result.GetTerm())
+		logger.Info("receive new term on AppendEntries response, fallback to follower")
+
+		return
+	}
+
+	r.lastContact[peerId] = time.Now()
+
+	if result.round > r.ackedRounds[peerId] {
+		r.ackedRounds[peerId] = result.round
+		r.lastAck[peerId] = result.sentAt
+		r.confirmReads()
+		r.extendLease()
+	}
+
+	if !result.GetSuccess() {
+		// the rejection is outdated if logs after the previous log are known to match,
+		// or if it is not the response of the AppendEntries probing `nextIndex`
+		if prevLogId < r.matchIndex[peerId] || (pr.probe && prevLogId+1 != r.nextIndex[peerId]) {
+			return
+		}
+
+		// if failed, skip all logs of the conflicting term and probe again
+		nextIndex := result.GetConflictIndex()
+		if result.GetConflictTerm() != 0 {
+			if lastLogId, ok := r.findLastLogOfTerm(result.GetConflictTerm()); ok {
+				nextIndex = lastLogId + 1
+			}
+		}
+
+		matchIndex := r.matchIndex[peerId]
+		if nextIndex <= matchIndex {
+			nextIndex = matchIndex + 1
+		}
+		r.setNextAndMatchIndex(peerId, nextIndex, matchIndex)
+		pr.becomeProbe()
+
+		logger.Info("append entries failed, decrease next index",
+			zap.Uint64("conflictTerm", result.GetConflictTerm()),
+			zap.Uint64("conflictIndex", result.GetConflictIndex()),
+			zap.Uint64("nextIndex", nextIndex),
+			zap.Uint64("matchIndex", matchIndex))
+
+		return
+	}
+
+	// logs of the peer match logs of the leader up to and including the last entry in the request
+	lastLogId := prevLogId + uint64(len(entries))
+	pr.ack(lastLogId)
+
+	nextIndex, matchIndex := r.nextIndex[peerId], r.matchIndex[peerId]
+	if lastLogId > matchIndex {
+		matchIndex = lastLogId
+	}
+	if nextIndex <= matchIndex {
+		nextIndex = matchIndex + 1
+	}
+
+	// the matching log is found, send entries optimistically
+	if pr.probe && nextIndex == matchIndex+1 {
+		pr.probe = false
+	}
+
+	if matchIndex != r.matchIndex[peerId] {
+		r.setNextAndMatchIndex(peerId, nextIndex, matchIndex)
+
+		logger.Info("append entries successfully, set next index and match index",
+			zap.Uint64("nextIndex", nextIndex),
+			zap.Uint64("matchIndex", matchIndex))
+
+		r.sendTimeoutNowIfReady()
+	}
+
+	r.advanceCommitIndex()
+}
@@ -0,0 +1,108 @@
+package raft
+
+import (
+	"testing"
+
+	"github.com/justin0u0/raft/pb"
+	"go.uber.org/zap"
+)
+
+func TestProgressInflightWindow(t *testing.T) {
+	pr := &progress{probe: true}
+
+	// only one AppendEntries is in flight while probing
+	pr.inflights = append(pr.inflights, 3)
+	if !pr.paused(4) {
+		t.Fatalf("expect the progress is paused while probing")
+	}
+
+	pr.ack(3)
+	pr.probe = false
+	for _, id := range []uint64{5, 7, 9, 11} {
+		if pr.paused(4) {
+			t.Fatalf("expect the progress is not paused with %d inflights", len(pr.inflights))
+		}
+		pr.inflights = append(pr.inflights, id)
+	}
+
+	if !pr.paused(4) {
+		t.Fatalf("expect the progress is paused when the window is full")
+	}
+
+	// acks free all inflights up to the matched log
+	pr.ack(8)
+	if len(pr.inflights) != 2 || pr.inflights[0] != 9 {
+		t.Fatalf("expect inflights [9 11], got %v", pr.inflights)
+	}
+
+	pr.becomeProbe()
+	if pr.paused(4) || len(pr.inflights) != 0 {
+		t.Fatalf("expect no inflights after rejection")
+	}
+}
+
+func TestGetLogsLimited(t *testing.T) {
+	p := NewMemoryPersister()
+	rs := &raftState{logStore: p, stableStore: p, snapshotStore: p, storage: newStorage(p, zap.NewNop())}
+
+	logs := []*pb.Entry{}
+	for id := uint64(1); id <= 10; id++ {
+		logs = append(logs, &pb.Entry{Id: id, Term: 1, Data: make([]byte, 100)})
+	}
+	logs[5].Data = make([]byte, 1000)
+
+	if err := rs.appendLogs(logs); err != nil {
+		t.Fatalf("fail to append logs: %v", err)
+	}
+
+	tests := []struct {
+		startId    uint64
+		maxEntries int
+		maxSize    int
+		lastId     uint64
+	}{
+		{startId: 1, maxEntries: 3, maxSize: 1 << 20, lastId: 3},
+		{startId: 9, maxEntries: 3, maxSize: 1 << 20, lastId: 10},
+		{startId: 1, maxEntries: 10, maxSize: 350, lastId: 3},
+		// an entry larger than the size limit is sent alone
+		{startId: 6, maxEntries: 10, maxSize: 350, lastId: 6},
+	}
+
+	for _, test := range tests {
+		logs := rs.getLogsLimited(test.startId, test.maxEntries, test.maxSize)
+		if len(logs) == 0 || logs[0].GetId() != test.startId || logs[len(logs)-1].GetId() != test.lastId {
+			t.Fatalf("expect logs between %d and %d, got %d logs", test.startId, test.lastId, len(logs))
+		}
+	}
+}
+
+func TestHeartbeatBehindCommitIndex(t *testing.T) {
+	r := NewRaft(2, map[uint32]Peer{1: &peer{}}, NewMemoryPersister(), nil, &Config{}, zap.NewNop())
+	if err := r.loadRaftState(); err != nil {
+		t.Fatal("fail to load raft state:", err)
+	}
+
+	entries := []*pb.Entry{}
+	for id := uint64(1); id <= 8; id++ {
+		entries = append(entries, &pb.Entry{Id: id, Term: 1})
+	}
+
+	req := &pb.AppendEntriesRequest{Term: 1, LeaderId: 1, LeaderCommitId: 5, Entries: entries}
+	if _, err := r.appendEntries(req); err != nil {
+		t.Fatal("fail to append entries:", err)
+	}
+
+	// the pipelined heartbeat carries the match index of the leader, which is behind the commit index of the follower
+	heartbeat := &pb.AppendEntriesRequest{Term: 1, LeaderId: 1, LeaderCommitId: 6, PrevLogId: 3, PrevLogTerm: 1}
+	resp, err := r.appendEntries(heartbeat)
+	if err != nil {
+		t.Fatal("fail to append entries:", err)
+	}
+
+	if !resp.GetSuccess() {
+		t.Fatal("expect the heartbeat to succeed")
+	}
+	if r.commitIndex != 5 {
+		t.Fatalf("expect the commit index to stay at 5, got %d", r.commitIndex)
+	}
+}
@@ -0,0 +1,217 @@
+package raft
+
+import (
+	"context"
+	"time"
+
+	"github.com/justin0u0/raft/pb"
+	"go.uber.org/zap"
+)
+
+const (
+	// minReplicationBackoff is the backoff after the first failed RPC to a peer, doubled on each failure
+	minReplicationBackoff = 10 * time.Millisecond
+)
+
+// replicator sends requests of the leader to a peer in order in its own goroutine,
+// so a slow or unreachable peer neither blocks the leader nor delays other peers
+type replicator struct {
+	peerId uint32
+	peer   Peer
+
+	// requestCh queues requests to send, a request is dropped instead of blocking the leader if it is full
+	requestCh chan *replicationRequest
+	// timeout is the deadline of each RPC
+	timeout time.Duration
+	// maxBackoff bounds the backoff after failed RPCs
+	maxBackoff time.Duration
+	// idleTimeout is the time the pipeline is kept open without sending requests, it is longer than timeout
+	// so requests in flight are either responded or failed before the pipeline is closed
+	idleTimeout time.Duration
+
+	// pipelined is true if AppendEntries are streamed by AppendEntriesPipeline instead of unary RPCs,
+	// and pipeline is the stream, nil if it is not opened yet or broken
+	pipelined bool
+	pipeline  *pipeline
+
+	appendEntriesResultCh   chan *appendEntriesResult
+	installSnapshotResultCh chan *installSnapshotResult
+
+	logger *zap.Logger
+}
+
+// replicationRequest is either an AppendEntries or an InstallSnapshot request
+type replicationRequest struct {
+	appendEntries   *pb.AppendEntriesRequest
+	installSnapshot *pb.InstallSnapshotRequest
+	// round and sentAt are the heartbeat round of an AppendEntries, zero if it is not sent by a heartbeat
+	round  uint64
+	sentAt time.Time
+}
+
+// getReplicator gets the replicator of the peer and starts it if it is not started in the current term,
+// replicators stop when the context is done
+func (r *Raft) getReplicator(
+	ctx context.Context,
+	peerId uint32,
+	peer Peer,
+	appendEntriesResultCh chan *appendEntriesResult,
+	installSnapshotResultCh chan *installSnapshotResult,
+) *replicator {
+	if rp, ok := r.replicators[peerId]; ok {
+		return rp
+	}
+
+	rp := &replicator{
+		peerId: peerId,
+		peer:   peer,
+		// the window of AppendEntries with entries, a heartbeat and an InstallSnapshot
+		requestCh:               make(chan *replicationRequest, r.config.maxInflightAppendEntries()+2),
+		timeout:                 r.config.replicationTimeout(),
+		maxBackoff:              r.config.HeartbeatInterval,
+		idleTimeout:             2 * r.config.replicationTimeout(),
+		pipelined:               !r.config.DisablePipeline,
+		appendEntriesResultCh:   appendEntriesResultCh,
+		installSnapshotResultCh: installSnapshotResultCh,
+		logger:                  r.logger.With(zap.Uint32("peer", peerId)),
+	}
+	r.replicators[peerId] = rp
+
+	go rp.run(ctx)
+
+	return rp
+}
+
+// enqueue queues the request without blocking, and reports if the request is queued
+func (rp *replicator) enqueue(req *replicationRequest) bool {
+	select {
+	case rp.requestCh <- req:
+		return true
+	default:
+		return false
+	}
+}
+
+func (rp *replicator) run(ctx context.Context) {
+	failures := 0
+
+	idleTimer := time.NewTimer(rp.idleTimeout)
+	defer idleTimer.Stop()
+
+	for {
+		var pipelineDoneCh <-chan struct{}
+		var idleCh <-chan time.Time
+		if rp.pipeline != nil {
+			pipelineDoneCh = rp.pipeline.doneCh
+			idleCh = idleTimer.C
+		}
+
+		var err error
+		select {
+		case <-ctx.Done():
+			return
+		case <-pipelineDoneCh:
+			err = rp.closePipeline(ctx)
+		case <-idleCh:
+			rp.closeIdlePipeline(ctx)
+		case req := <-rp.requestCh:
+			resetTimer(idleTimer, rp.idleTimeout)
+			err = rp.send(ctx, req)
+		}
+
+		if err == nil {
+			failures = 0
+			continue
+		}
+
+		// back off before sending the next request to the unreachable peer
+		failures++
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(rp.backoff(failures)):
+		}
+	}
+}
+
+// send sends the request and passes the result to the leader, AppendEntries are sent by the pipeline
+// without waiting for responses if it is enabled
+func (rp *replicator) send(ctx context.Context, req *replicationRequest) error {
+	if req.installSnapshot != nil {
+		return rp.sendInstallSnapshot(ctx, req)
+	}
+
+	if rp.pipelined {
+		return rp.sendPipelined(ctx, req)
+	}
+
+	return rp.sendAppendEntries(ctx, req)
+}
+
+func (rp *replicator) sendAppendEntries(ctx context.Context, req *replicationRequest) error {
+	rpcCtx, cancel := context.WithTimeout(ctx, rp.timeout)
+	defer cancel()
+
+	resp, err := rp.peer.AppendEntries(rpcCtx, req.appendEntries)
+	rp.deliverAppendEntries(ctx, req, resp, err)
+
+	return err
+}
+
+func (rp *replicator) sendInstallSnapshot(ctx context.Context, req *replicationRequest) error {
+	rpcCtx, cancel := context.WithTimeout(ctx, rp.timeout)
+	defer cancel()
+
+	resp, err := rp.peer.InstallSnapshot(rpcCtx, req.installSnapshot)
+	if err != nil {
+		rp.logger.Error("fail to send InstallSnapshot RPC", zap.Error(err))
+	}
+
+	result := &installSnapshotResult{
+		InstallSnapshotResponse: resp,
+		req:                     req.installSnapshot,
+		peerId:                  rp.peerId,
+		err:                     err,
+	}
+
+	select {
+	case <-ctx.Done():
+	case rp.installSnapshotResultCh <- result:
+	}
+
+	return err
+}
+
+// deliverAppendEntries passes the result of the AppendEntries to the leader
+func (rp *replicator) deliverAppendEntries(ctx context.Context, req *replicationRequest, resp *pb.AppendEntriesResponse, err error) {
+	if err != nil {
+		rp.logger.Error("fail to send AppendEntries RPC", zap.Error(err))
+	}
+
+	result := &appendEntriesResult{
+		AppendEntriesResponse: resp,
+		req:                   req.appendEntries,
+		peerId:                rp.peerId,
+		round:                 req.round,
+		sentAt:                req.sentAt,
+		err:                   err,
+	}
+
+	select {
+	case <-ctx.Done():
+	case rp.appendEntriesResultCh <- result:
+	}
+}
+
+func (rp *replicator) backoff(failures int) time.Duration {
+	backoff := minReplicationBackoff
+	for i := 1; i < failures && backoff < rp.maxBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > rp.maxBackoff {
+		backoff = rp.maxBackoff
+	}
+
+	return backoff
+}
@@ -0,0 +1,164 @@
+package raft
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/justin0u0/raft/pb"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// unaryPeer is a peer that does not support AppendEntriesPipeline
+type unaryPeer struct {
+	Peer
+	calls int
+}
+
+func (p *unaryPeer) AppendEntries(ctx context.Context, in *pb.AppendEntriesRequest, opts ...grpc.CallOption) (*pb.AppendEntriesResponse, error) {
+	p.calls++
+	return &pb.AppendEntriesResponse{Term: in.GetTerm(), Success: true}, nil
+}
+
+func (p *unaryPeer) AppendEntriesPipeline(ctx context.Context, opts ...grpc.CallOption) (pb.Raft_AppendEntriesPipelineClient, error) {
+	return &unimplementedStream{}, nil
+}
+
+// unimplementedStream fails like a stream to a server without AppendEntriesPipeline
+type unimplementedStream struct {
+	grpc.ClientStream
+}
+
+func (s *unimplementedStream) Send(*pb.AppendEntriesRequest) error {
+	return nil
+}
+
+func (s *unimplementedStream) Recv() (*pb.AppendEntriesResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method AppendEntriesPipeline not implemented")
+}
+
+func TestReplicatorFallbackToUnary(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	peer := &unaryPeer{}
+	rp := &replicator{
+		peerId:                  2,
+		peer:                    peer,
+		requestCh:               make(chan *replicationRequest, 2),
+		timeout:                 time.Second,
+		maxBackoff:              10 * time.Millisecond,
+		idleTimeout:             2 * time.Second,
+		pipelined:               true,
+		appendEntriesResultCh:   make(chan *appendEntriesResult, 2),
+		installSnapshotResultCh: make(chan *installSnapshotResult, 2),
+		logger:                  zap.NewNop(),
+	}
+	go rp.run(ctx)
+
+	// the request on the unsupported pipeline fails
+	rp.enqueue(&replicationRequest{appendEntries: &pb.AppendEntriesRequest{Term: 1}})
+	if result := <-rp.appendEntriesResultCh; status.Code(result.err) != codes.Unimplemented {
+		t.Fatalf("expect the request fails with Unimplemented, got %v", result.err)
+	}
+
+	// following requests are sent by unary AppendEntries
+	rp.enqueue(&replicationRequest{appendEntries: &pb.AppendEntriesRequest{Term: 1}})
+	if result := <-rp.appendEntriesResultCh; result.err != nil || !result.GetSuccess() {
+		t.Fatalf("expect the request succeeds by unary AppendEntries, got %v", result.err)
+	}
+
+	if peer.calls != 1 {
+		t.Fatalf("expect 1 unary AppendEntries, got %d", peer.calls)
+	}
+}
+
+// pipelinePeer is a peer that responds AppendEntries streamed by AppendEntriesPipeline
+type pipelinePeer struct {
+	Peer
+	stream *echoStream
+}
+
+func (p *pipelinePeer) AppendEntriesPipeline(ctx context.Context, opts ...grpc.CallOption) (pb.Raft_AppendEntriesPipelineClient, error) {
+	p.stream = &echoStream{ctx: ctx, reqCh: make(chan *pb.AppendEntriesRequest, 1), closedCh: make(chan struct{})}
+
+	return p.stream, nil
+}
+
+// echoStream responds each request with success until the stream is canceled
+type echoStream struct {
+	grpc.ClientStream
+	ctx      context.Context
+	reqCh    chan *pb.AppendEntriesRequest
+	closedCh chan struct{}
+}
+
+func (s *echoStream) Send(req *pb.AppendEntriesRequest) error {
+	s.reqCh <- req
+	return nil
+}
+
+func (s *echoStream) Recv() (*pb.AppendEntriesResponse, error) {
+	select {
+	case <-s.ctx.Done():
+		return nil, status.FromContextError(s.ctx.Err()).Err()
+	case req := <-s.reqCh:
+		return &pb.AppendEntriesResponse{Term: req.GetTerm(), Success: true}, nil
+	}
+}
+
+func (s *echoStream) CloseSend() error {
+	close(s.closedCh)
+	return nil
+}
+
+func TestReplicatorClosesIdlePipeline(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	peer := &pipelinePeer{}
+	rp := &replicator{
+		peerId:                  2,
+		peer:                    peer,
+		requestCh:               make(chan *replicationRequest, 2),
+		timeout:                 50 * time.Millisecond,
+		maxBackoff:              10 * time.Millisecond,
+		idleTimeout:             100 * time.Millisecond,
+		pipelined:               true,
+		appendEntriesResultCh:   make(chan *appendEntriesResult, 2),
+		installSnapshotResultCh: make(chan *installSnapshotResult, 2),
+		logger:                  zap.NewNop(),
+	}
+	go rp.run(ctx)
+
+	rp.enqueue(&replicationRequest{appendEntries: &pb.AppendEntriesRequest{Term: 1}})
+	if result := <-rp.appendEntriesResultCh; result.err != nil || !result.GetSuccess() {
+		t.Fatalf("expect the request succeeds by the pipeline, got %v", result.err)
+	}
+
+	// the stream is closed once no request is sent within the idle timeout
+	stream := peer.stream
+	select {
+	case <-stream.closedCh:
+	case <-time.After(time.Second):
+		t.Fatal("expect the idle pipeline is closed")
+	}
+
+	select {
+	case <-stream.ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("expect the context of the idle pipeline is canceled")
+	}
+
+	// a new pipeline is opened for the next request
+	rp.enqueue(&replicationRequest{appendEntries: &pb.AppendEntriesRequest{Term: 1}})
+	if result := <-rp.appendEntriesResultCh; result.err != nil || !result.GetSuccess() {
+		t.Fatalf("expect the request succeeds by a new pipeline, got %v", result.err)
+	}
+	if peer.stream == stream {
+		t.Fatal("expect a new pipeline is opened")
+	}
+}
@@ -4,8 +4,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 
 	"github.com/justin0u0/raft/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type rpcResponse struct {
@@ -14,6 +17,8 @@ type rpcResponse struct {
 }
 
 type rpc struct {
+	// ctx is the context of the caller
+	ctx    context.Context
 	req    interface{}
 	respCh chan<- *rpcResponse
 }
@@ -23,14 +28,126 @@ func (r *rpc) respond(resp interface{}, err error) {
 }
 
 var (
-	errRPCTimeout           = errors.New("rpc timeout")
-	errResponseTypeMismatch = errors.New("response type mismatch")
-	errInvalidRPCType       = errors.New("invalid rpc type")
-	errNotLeader            = errors.New("not leader")
+	errRPCTimeout           = newRPCError(codes.DeadlineExceeded, "rpc timeout")
+	errResponseTypeMismatch = newRPCError(codes.Internal, "response type mismatch")
+	errInvalidRPCType       = newRPCError(codes.Internal, "invalid rpc type")
+	errNotLeader            = newRPCError(codes.FailedPrecondition, "not leader")
+	errRaftStopped          = newRPCError(codes.Unavailable, "raft server is stopped")
 )
 
+// rpcError is an error that reaches gRPC clients with the status code
+type rpcError struct {
+	code    codes.Code
+	message string
+}
+
+func newRPCError(code codes.Code, message string) error {
+	return &rpcError{code: code, message: message}
+}
+
+func (e *rpcError) Error() string {
+	return e.message
+}
+
+func (e *rpcError) GRPCStatus() *status.Status {
+	return status.New(e.code, e.message)
+}
+
+// toRPCError converts the error returned to gRPC clients, since status.FromError does not unwrap errors,
+// a wrapped error keeps the status code of the error it wraps, and other errors are internal
+func toRPCError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if _, ok := err.(interface{ GRPCStatus() *status.Status }); ok {
+		return err
+	}
+
+	var s interface{ GRPCStatus() *status.Status }
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return errRPCTimeout
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.As(err, &s):
+		return status.Error(s.GRPCStatus().Code(), err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// NotLeaderError is returned when the server is not the leader, with the hint of the leader known by the server
+type NotLeaderError struct {
+	// LeaderId is zero if the leader is unknown
+	LeaderId      uint32
+	LeaderAddress string
+	Term          uint64
+}
+
+func (e *NotLeaderError) Error() string {
+	if e.LeaderId == 0 {
+		return "not leader, leader is unknown"
+	}
+
+	return fmt.Sprintf("not leader, leader is %d at %q", e.LeaderId, e.LeaderAddress)
+}
+
+// Is makes errors.Is(err, errNotLeader) true
+func (e *NotLeaderError) Is(target error) bool {
+	return target == errNotLeader
+}
+
+// GRPCStatus returns the status with the LeaderHint detail, so clients can redirect to the leader
+func (e *NotLeaderError) GRPCStatus() *status.Status {
+	s := status.New(codes.FailedPrecondition, e.Error())
+
+	ds, err := s.WithDetails(&pb.LeaderHint{
+		LeaderId:      e.LeaderId,
+		LeaderAddress: e.LeaderAddress,
+		Term:          e.Term,
+	})
+	if err != nil {
+		return s
+	}
+
+	return ds
+}
+
+// LeaderHintFromError gets the LeaderHint detail from the error returned by a gRPC client,
+// it returns false if the error does not have one
+func LeaderHintFromError(err error) (*pb.LeaderHint, bool) {
+	s, ok := status.FromError(err)
+	if !ok {
+		return nil, false
+	}
+
+	for _, detail := range s.Details() {
+		if hint, ok := detail.(*pb.LeaderHint); ok {
+			return hint, true
+		}
+	}
+
+	return nil, false
+}
+
+func (r *Raft) notLeaderError() error {
+	return &NotLeaderError{
+		LeaderId:      r.leaderId,
+		LeaderAddress: r.getServer(r.leaderId).GetAddress(),
+		Term:          r.currentTerm,
+	}
+}
+
+// ApplyCommand appends the command to the logs of the leader, if the request waits for the command,
+// it returns after the command is committed and applied with the result responded by the application.
 func (r *Raft) ApplyCommand(ctx context.Context, req *pb.ApplyCommandRequest) (*pb.ApplyCommandResponse, error) {
-	rpcResp, err := r.dispatchRPCRequest(ctx, req)
+	var future *applyFuture
+	if req.GetWait() {
+		future = newApplyFuture()
+	}
+
+	rpcResp, err := r.dispatchRPCRequest(ctx, &applyCommandRequest{ApplyCommandRequest: req, future: future})
 	if err != nil {
 		return nil, err
 	}
@@ -40,8 +157,129 @@ func (r *Raft) ApplyCommand(ctx context.Context, req *pb.ApplyCommandRequest) (*
 		return nil, errResponseTypeMismatch
 	}
 
-	if err := r.saveRaftState(r.persister); err != nil {
-		return nil, fmt.Errorf("fail to save raft state: %w", err)
+	if err := r.saveRaftState(ctx); err != nil {
+		return nil, toRPCError(fmt.Errorf("fail to save raft state: %w", err))
+	}
+
+	// a duplicate command is responded with the cached result without appending a new entry
+	if future != nil && resp.GetEntry() != nil {
+		result, err := future.wait(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		resp.Result = result
+	}
+
+	return resp, nil
+}
+
+// ReadIndex returns after the leadership is confirmed and logs up to and including the read index are applied,
+// so the application can serve a linearizable read from its state
+func (r *Raft) ReadIndex(ctx context.Context, req *pb.ReadIndexRequest) (*pb.ReadIndexResponse, error) {
+	rpcResp, err := r.dispatchRPCRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, ok := rpcResp.(*pb.ReadIndexResponse)
+	if !ok {
+		return nil, errResponseTypeMismatch
+	}
+
+	if err := r.waitApplied(ctx, resp.GetReadIndex()); err != nil {
+		return nil, errRPCTimeout
+	}
+
+	return resp, nil
+}
+
+// LeaseRead is the same as ReadIndex but serves the read from the leader lease without a heartbeat round if it is valid
+func (r *Raft) LeaseRead(ctx context.Context, req *pb.LeaseReadRequest) (*pb.LeaseReadResponse, error) {
+	rpcResp, err := r.dispatchRPCRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, ok := rpcResp.(*pb.LeaseReadResponse)
+	if !ok {
+		return nil, errResponseTypeMismatch
+	}
+
+	if err := r.waitApplied(ctx, resp.GetReadIndex()); err != nil {
+		return nil, errRPCTimeout
+	}
+
+	return resp, nil
+}
+
+func (r *Raft) AddServer(ctx context.Context, req *pb.AddServerRequest) (*pb.AddServerResponse, error) {
+	rpcResp, err := r.dispatchRPCRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, ok := rpcResp.(*pb.AddServerResponse)
+	if !ok {
+		return nil, errResponseTypeMismatch
+	}
+
+	if err := r.saveRaftState(ctx); err != nil {
+		return nil, toRPCError(fmt.Errorf("fail to save raft state: %w", err))
+	}
+
+	return resp, nil
+}
+
+func (r *Raft) RemoveServer(ctx context.Context, req *pb.RemoveServerRequest) (*pb.RemoveServerResponse, error) {
+	rpcResp, err := r.dispatchRPCRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, ok := rpcResp.(*pb.RemoveServerResponse)
+	if !ok {
+		return nil, errResponseTypeMismatch
+	}
+
+	if err := r.saveRaftState(ctx); err != nil {
+		return nil, toRPCError(fmt.Errorf("fail to save raft state: %w", err))
+	}
+
+	return resp, nil
+}
+
+func (r *Raft) PromoteLearner(ctx context.Context, req *pb.PromoteLearnerRequest) (*pb.PromoteLearnerResponse, error) {
+	rpcResp, err := r.dispatchRPCRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, ok := rpcResp.(*pb.PromoteLearnerResponse)
+	if !ok {
+		return nil, errResponseTypeMismatch
+	}
+
+	if err := r.saveRaftState(ctx); err != nil {
+		return nil, toRPCError(fmt.Errorf("fail to save raft state: %w", err))
+	}
+
+	return resp, nil
+}
+
+func (r *Raft) TransferLeadership(ctx context.Context, req *pb.TransferLeadershipRequest) (*pb.TransferLeadershipResponse, error) {
+	rpcResp, err := r.dispatchRPCRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, ok := rpcResp.(*pb.TransferLeadershipResponse)
+	if !ok {
+		return nil, errResponseTypeMismatch
+	}
+
+	if err := r.saveRaftState(ctx); err != nil {
+		return nil, toRPCError(fmt.Errorf("fail to save raft state: %w", err))
 	}
 
 	return resp, nil
@@ -58,13 +296,93 @@ func (r *Raft) AppendEntries(ctx context.Context, req *pb.AppendEntriesRequest)
 		return nil, errResponseTypeMismatch
 	}
 
-	if err := r.saveRaftState(r.persister); err != nil {
-		return nil, fmt.Errorf("fail to save raft state: %w", err)
+	if err := r.saveRaftState(ctx); err != nil {
+		return nil, toRPCError(fmt.Errorf("fail to save raft state: %w", err))
 	}
 
 	return resp, nil
 }
 
+// AppendEntriesPipeline handles AppendEntries streamed by the leader in order, and streams responses back
+// in the same order once logs appended by each request are durable, so later requests are handled
+// while earlier ones are waiting for fsync. The stream stays open until the leader closes it or the raft server stops.
+func (r *Raft) AppendEntriesPipeline(stream pb.Raft_AppendEntriesPipelineServer) error {
+	// waiting for logs to be durable is aborted once the raft server stops
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-r.doneCh:
+			cancel()
+		}
+	}()
+
+	// requests are handled until an error, which is the last one in respCh
+	respCh := make(chan *rpcResponse, pipelineBufferSize)
+
+	go func() {
+		defer close(respCh)
+
+		for {
+			rpcResp := &rpcResponse{}
+
+			req, err := stream.Recv()
+			if err != nil {
+				rpcResp.err = err
+			} else {
+				rpcResp.resp, rpcResp.err = r.dispatchRPCRequest(ctx, req)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case respCh <- rpcResp:
+			}
+
+			if rpcResp.err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		var rpcResp *rpcResponse
+		select {
+		case <-r.doneCh:
+			return errRaftStopped
+		case rpcResp = <-respCh:
+		}
+
+		// respCh is closed without an error once the stream is canceled
+		if rpcResp == nil {
+			return status.FromContextError(ctx.Err()).Err()
+		}
+
+		if err := rpcResp.err; err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+
+			return err
+		}
+
+		resp, ok := rpcResp.resp.(*pb.AppendEntriesResponse)
+		if !ok {
+			return errResponseTypeMismatch
+		}
+
+		if err := r.saveRaftState(ctx); err != nil {
+			return toRPCError(fmt.Errorf("fail to save raft state: %w", err))
+		}
+
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+}
+
 func (r *Raft) RequestVote(ctx context.Context, req *pb.RequestVoteRequest) (*pb.RequestVoteResponse, error) {
 	rpcResp, err := r.dispatchRPCRequest(ctx, req)
 	if err != nil {
@@ -76,8 +394,44 @@ func (r *Raft) RequestVote(ctx context.Context, req *pb.RequestVoteRequest) (*pb
 		return nil, errResponseTypeMismatch
 	}
 
-	if err := r.saveRaftState(r.persister); err != nil {
-		return nil, fmt.Errorf("fail to save raft state: %w", err)
+	if err := r.saveRaftState(ctx); err != nil {
+		return nil, toRPCError(fmt.Errorf("fail to save raft state: %w", err))
+	}
+
+	return resp, nil
+}
+
+func (r *Raft) InstallSnapshot(ctx context.Context, req *pb.InstallSnapshotRequest) (*pb.InstallSnapshotResponse, error) {
+	rpcResp, err := r.dispatchRPCRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, ok := rpcResp.(*pb.InstallSnapshotResponse)
+	if !ok {
+		return nil, errResponseTypeMismatch
+	}
+
+	if err := r.saveRaftState(ctx); err != nil {
+		return nil, toRPCError(fmt.Errorf("fail to save raft state: %w", err))
+	}
+
+	return resp, nil
+}
+
+func (r *Raft) TimeoutNow(ctx context.Context, req *pb.TimeoutNowRequest) (*pb.TimeoutNowResponse, error) {
+	rpcResp, err := r.dispatchRPCRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, ok := rpcResp.(*pb.TimeoutNowResponse)
+	if !ok {
+		return nil, errResponseTypeMismatch
+	}
+
+	if err := r.saveRaftState(ctx); err != nil {
+		return nil, toRPCError(fmt.Errorf("fail to save raft state: %w", err))
 	}
 
 	return resp, nil
@@ -85,14 +439,23 @@ func (r *Raft) RequestVote(ctx context.Context, req *pb.RequestVoteRequest) (*pb
 
 func (r *Raft) dispatchRPCRequest(ctx context.Context, req interface{}) (interface{}, error) {
 	respCh := make(chan *rpcResponse, 1)
-	r.rpcCh <- &rpc{req: req, respCh: respCh}
 
 	select {
 	case <-ctx.Done():
 		return nil, errRPCTimeout
+	case <-r.doneCh:
+		return nil, errRaftStopped
+	case r.rpcCh <- &rpc{ctx: ctx, req: req, respCh: respCh}:
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, errRPCTimeout
+	case <-r.doneCh:
+		return nil, errRaftStopped
 	case rpcResp := <-respCh:
 		if err := rpcResp.err; err != nil {
-			return nil, err
+			return nil, toRPCError(err)
 		}
 
 		return rpcResp.resp, nil
@@ -101,12 +464,37 @@ func (r *Raft) dispatchRPCRequest(ctx context.Context, req interface{}) (interfa
 
 func (r *Raft) handleRPCRequest(rpc *rpc) {
 	switch req := rpc.req.(type) {
-	case *pb.ApplyCommandRequest:
-		rpc.respond(r.applyCommand(req))
+	case *applyCommandRequest:
+		if r.state != Leader && r.config.ForwardApplyCommand {
+			// responded after the leader responds
+			r.forwardApplyCommand(rpc, req)
+		} else {
+			rpc.respond(r.applyCommand(req))
+		}
+	case *pb.ReadIndexRequest:
+		// responded after the leadership is confirmed
+		r.readIndex(rpc, req)
+	case *pb.LeaseReadRequest:
+		r.leaseRead(rpc, req)
+	case *pb.AddServerRequest:
+		rpc.respond(r.addServer(req))
+	case *pb.RemoveServerRequest:
+		rpc.respond(r.removeServer(req))
+	case *pb.PromoteLearnerRequest:
+		rpc.respond(r.promoteLearner(req))
+	case *pb.TransferLeadershipRequest:
+		// responded after the transfer finishes
+		r.transferLeadership(rpc, req)
 	case *pb.AppendEntriesRequest:
 		rpc.respond(r.appendEntries(req))
 	case *pb.RequestVoteRequest:
 		rpc.respond(r.requestVote(req))
+	case *pb.InstallSnapshotRequest:
+		rpc.respond(r.installSnapshot(req))
+	case *pb.TimeoutNowRequest:
+		rpc.respond(r.timeoutNow(req))
+	case *snapshotRequest:
+		rpc.respond(nil, r.snapshot(req))
 	default:
 		rpc.respond(nil, errInvalidRPCType)
 	}
@@ -0,0 +1,36 @@
+package raft
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestToRPCError(t *testing.T) {
+	tests := []struct {
+		err  error
+		code codes.Code
+	}{
+		{err: errNotLeader, code: codes.FailedPrecondition},
+		{err: &NotLeaderError{LeaderId: 1}, code: codes.FailedPrecondition},
+		{err: fmt.Errorf("fail to save snapshot and compact logs: %w", errSnapshotOutdated), code: codes.FailedPrecondition},
+		{err: fmt.Errorf("fail to save raft state: %w", context.DeadlineExceeded), code: codes.DeadlineExceeded},
+		{err: fmt.Errorf("fail to save raft state: %w", errors.New("disk failure")), code: codes.Internal},
+	}
+
+	for _, test := range tests {
+		if code := status.Code(toRPCError(test.err)); code != test.code {
+			t.Fatalf("status code of %q should be %s, got %s", test.err, test.code, code)
+		}
+	}
+
+	// the error is kept if it has a status, so the leader hint is not lost
+	var notLeaderErr *NotLeaderError
+	if err := toRPCError(&NotLeaderError{LeaderId: 1}); !errors.As(err, &notLeaderErr) {
+		t.Fatalf("error with a status should be kept, got %v", err)
+	}
+}
@@ -0,0 +1,174 @@
+package raft
+
+import (
+	"sort"
+	"time"
+
+	"github.com/justin0u0/raft/pb"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	errSequenceOutdated = newRPCError(codes.FailedPrecondition, "sequence is older than the latest command of the client")
+	errSessionExpired   = newRPCError(codes.FailedPrecondition, "session of the client is expired")
+)
+
+// sessions are part of the replicated state, they are updated when commands are applied,
+// so all servers detect the same duplicate commands
+
+// isDuplicate checks if the command is already applied in the client session
+func isDuplicate(sessions map[uint64]*pb.Session, log *pb.Entry) bool {
+	session, ok := sessions[log.GetClientId()]
+
+	return ok && log.GetSequence() <= session.GetSequence()
+}
+
+// isExpired checks if the command belongs to a session that is expired, sequences of a client start at 1,
+// so a command after the first one of a client without a session must not open a new session implicitly
+func isExpired(sessions map[uint64]*pb.Session, log *pb.Entry) bool {
+	_, ok := sessions[log.GetClientId()]
+
+	return !ok && log.GetSequence() > 1
+}
+
+// updateSessions updates sessions by the applied log, result is the result of the command
+func updateSessions(sessions map[uint64]*pb.Session, log *pb.Entry, result []byte) {
+	switch log.GetType() {
+	case pb.EntryType_COMMAND:
+		if log.GetClientId() == 0 || isDuplicate(sessions, log) || isExpired(sessions, log) {
+			return
+		}
+
+		sessions[log.GetClientId()] = &pb.Session{
+			ClientId: log.GetClientId(),
+			Sequence: log.GetSequence(),
+			Result:   result,
+		}
+
+	case pb.EntryType_EXPIRE_SESSIONS:
+		expire := &pb.ExpireSessions{}
+		if err := proto.Unmarshal(log.GetData(), expire); err != nil {
+			return
+		}
+
+		for _, clientId := range expire.GetClientIds() {
+			delete(sessions, clientId)
+		}
+	}
+}
+
+func newSessions(sessions []*pb.Session) map[uint64]*pb.Session {
+	m := make(map[uint64]*pb.Session, len(sessions))
+	for _, session := range sessions {
+		m[session.GetClientId()] = session
+	}
+
+	return m
+}
+
+// getSessions gets sessions after applying logs up to and including the given log id
+//
+// Note that the given log id must be applied.
+func (rs *raftState) getSessions(id uint64) []*pb.Session {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	sessions := newSessions(rs.lastIncludedSessions)
+
+	for _, log := range rs.getLogs(rs.lastIncludedId + 1) {
+		if log.GetId() > id {
+			break
+		}
+
+		updateSessions(sessions, log, rs.sessionResults[log.GetId()])
+	}
+
+	list := make([]*pb.Session, 0, len(sessions))
+	for _, session := range sessions {
+		list = append(list, session)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].GetClientId() < list[j].GetClientId()
+	})
+
+	return list
+}
+
+// getSession gets the session of the client with the latest applied command
+func (rs *raftState) getSession(clientId uint64) *pb.Session {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	return rs.sessions[clientId]
+}
+
+func (rs *raftState) getSessionClientIds() []uint64 {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	clientIds := make([]uint64, 0, len(rs.sessions))
+	for clientId := range rs.sessions {
+		clientIds = append(clientIds, clientId)
+	}
+
+	return clientIds
+}
+
+// leader related
+
+// expireSessions appends a log to expire sessions without commands within the session timeout
+func (r *Raft) expireSessions() {
+	now := time.Now()
+
+	clientIds := []uint64{}
+	for _, clientId := range r.getSessionClientIds() {
+		lastActive, ok := r.sessionActivity[clientId]
+		if !ok {
+			// the session is not active since the leader is elected
+			r.sessionActivity[clientId] = now
+			continue
+		}
+
+		if now.Sub(lastActive) > r.config.SessionTimeout {
+			clientIds = append(clientIds, clientId)
+			delete(r.sessionActivity, clientId)
+		}
+	}
+
+	if len(clientIds) == 0 {
+		return
+	}
+
+	data, err := proto.Marshal(&pb.ExpireSessions{ClientIds: clientIds})
+	if err != nil {
+		r.logger.Error("fail to encode expired sessions", zap.Error(err))
+		return
+	}
+
+	lastLogId, _ := r.getLastLog()
+	e := &pb.Entry{
+		Id:        lastLogId + 1,
+		Term:      r.currentTerm,
+		Type:      pb.EntryType_EXPIRE_SESSIONS,
+		Data:      data,
+		Timestamp: now.UnixNano(),
+	}
+	if err := r.appendLogs([]*pb.Entry{e}); err != nil {
+		r.logger.Error("fail to append logs", zap.Error(err))
+		return
+	}
+	r.notifyReplicate()
+
+	r.logger.Info("expire client sessions", zap.Uint64s("clientIds", clientIds))
+}
+
+func (r *Raft) sessionExpiryCh() <-chan time.Time {
+	if r.config.SessionTimeout == 0 {
+		return nil
+	}
+
+	return time.After(r.config.SessionTimeout)
+}
@@ -0,0 +1,212 @@
+package raft
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/justin0u0/raft/pb"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+)
+
+// Snapshot is the state of the application that replaces all logs before and including `LastIncludedId`
+type Snapshot struct {
+	LastIncludedId   uint64
+	LastIncludedTerm uint64
+	Data             []byte
+}
+
+var (
+	errSnapshotOutdated   = newRPCError(codes.FailedPrecondition, "snapshot is older than the current one")
+	errSnapshotNotApplied = newRPCError(codes.FailedPrecondition, "snapshot contains logs that are not applied")
+)
+
+type snapshotRequest struct {
+	id   uint64
+	data []byte
+}
+
+// Snapshot tells raft that the application has taken a snapshot with all logs up to and including the given log id,
+// so raft can discard these logs.
+//
+// Note that Snapshot must not be called in the goroutine that consumes ApplyCh or SnapshotCh.
+func (r *Raft) Snapshot(ctx context.Context, id uint64, data []byte) error {
+	_, err := r.dispatchRPCRequest(ctx, &snapshotRequest{id: id, data: data})
+
+	return err
+}
+
+// SnapshotCh returns the channel of snapshots that the application must restore its state from,
+// it must be consumed along with ApplyCh, and it is nil if NewRaft is given an FSM.
+func (r *Raft) SnapshotCh() <-chan *Snapshot {
+	return r.snapshotCh
+}
+
+// restoreLastSnapshot restores the application from the persisted snapshot on startup
+func (r *Raft) restoreLastSnapshot() error {
+	_, data, err := r.snapshotStore.LoadSnapshot()
+	if err != nil {
+		return err
+	}
+
+	r.restoreSnapshot(&Snapshot{
+		LastIncludedId:   r.lastIncludedId,
+		LastIncludedTerm: r.lastIncludedTerm,
+		Data:             data,
+	})
+	r.notifyApply()
+
+	r.logger.Info("restore snapshot",
+		zap.Uint64("lastIncludedId", r.lastIncludedId),
+		zap.Uint64("lastIncludedTerm", r.lastIncludedTerm))
+
+	return nil
+}
+
+// RPC handlers
+
+func (r *Raft) snapshot(req *snapshotRequest) error {
+	if req.id <= r.lastIncludedId {
+		return errSnapshotOutdated
+	}
+
+	if req.id > r.getLastApplied() {
+		return errSnapshotNotApplied
+	}
+
+	term, _ := r.getLogTerm(req.id)
+	configuration, _ := r.getConfiguration(req.id)
+	if err := r.compactLogs(req.id, term, configuration, r.getSessions(req.id), req.data); err != nil {
+		return fmt.Errorf("fail to save snapshot and compact logs: %w", err)
+	}
+
+	r.logger.Info("take snapshot and compact logs",
+		zap.Uint64("lastIncludedId", r.lastIncludedId),
+		zap.Uint64("lastIncludedTerm", r.lastIncludedTerm),
+		zap.Uint64("lastLogId", r.getLastLogId()))
+
+	return nil
+}
+
+func (r *Raft) installSnapshot(req *pb.InstallSnapshotRequest) (*pb.InstallSnapshotResponse, error) {
+	if req.GetTerm() < r.currentTerm {
+		r.logger.Info("reject install snapshot since current term is older")
+
+		return &pb.InstallSnapshotResponse{Term: r.currentTerm}, nil
+	}
+
+	r.lastHeartbeat = time.Now()
+	r.lastLeaderContact = r.lastHeartbeat
+	r.lostQuorum = false
+
+	// increase term if receive a newer one
+	if req.GetTerm() > r.currentTerm {
+		r.toFollower(req.GetTerm())
+		r.logger.Info("increase term since receive a newer one", zap.Uint64("term", r.currentTerm))
+	}
+
+	if req.GetTerm() == r.currentTerm && r.state != Follower {
+		r.toFollower(req.GetTerm())
+		r.logger.Info("receive request from leader, fallback to follower", zap.Uint64("term", r.currentTerm))
+	}
+
+	if r.leaderId != req.GetLeaderId() {
+		r.setLeader(req.GetLeaderId())
+		r.logger.Info("found the leader of current term", zap.Uint32("leader", r.leaderId))
+
+		r.finishLeadershipTransfer()
+	}
+
+	if req.GetLastIncludedId() <= r.commitIndex {
+		r.logger.Info("ignore snapshot since logs in the snapshot are already committed",
+			zap.Uint64("lastIncludedId", req.GetLastIncludedId()),
+			zap.Uint64("commitIndex", r.commitIndex))
+
+		return &pb.InstallSnapshotResponse{Term: r.currentTerm}, nil
+	}
+
+	if err := r.compactLogs(req.GetLastIncludedId(), req.GetLastIncludedTerm(), req.GetConfiguration(), req.GetSessions(), req.GetData()); err != nil {
+		return nil, fmt.Errorf("fail to save snapshot and compact logs: %w", err)
+	}
+
+	r.syncPeers()
+	r.restoreSnapshot(&Snapshot{
+		LastIncludedId:   req.GetLastIncludedId(),
+		LastIncludedTerm: req.GetLastIncludedTerm(),
+		Data:             req.GetData(),
+	})
+	r.notifyApply()
+
+	r.logger.Info("install snapshot from leader",
+		zap.Uint64("lastIncludedId", r.lastIncludedId),
+		zap.Uint64("lastIncludedTerm", r.lastIncludedTerm),
+		zap.Uint64("lastLogId", r.getLastLogId()))
+
+	return &pb.InstallSnapshotResponse{Term: r.currentTerm}, nil
+}
+
+// leader related
+
+type installSnapshotResult struct {
+	*pb.InstallSnapshotResponse
+	req    *pb.InstallSnapshotRequest
+	peerId uint32
+	// err is the error of the RPC, the response is nil if it is set
+	err error
+}
+
+// sendInstallSnapshot queues the snapshot to the replicator, and reports if the snapshot is queued
+func (r *Raft) sendInstallSnapshot(rp *replicator) bool {
+	_, data, err := r.snapshotStore.LoadSnapshot()
+	if err != nil {
+		r.logger.Error("fail to load snapshot", zap.Error(err))
+		return false
+	}
+
+	req := &pb.InstallSnapshotRequest{
+		Term:             r.currentTerm,
+		LeaderId:         r.id,
+		LastIncludedId:   r.lastIncludedId,
+		LastIncludedTerm: r.lastIncludedTerm,
+		Data:             data,
+		Configuration:    r.lastIncludedConfiguration,
+		Sessions:         r.lastIncludedSessions,
+	}
+
+	return rp.enqueue(&replicationRequest{installSnapshot: req})
+}
+
+func (r *Raft) handleInstallSnapshotResult(result *installSnapshotResult) {
+	peerId := result.peerId
+	logger := r.logger.With(zap.Uint32("peer", peerId))
+
+	// the snapshot is sent again on the next heartbeat if it fails
+	r.getProgress(peerId).snapshotting = false
+	if result.err != nil {
+		return
+	}
+
+	if result.GetTerm() > r.currentTerm {
+		r.toFollower(result.GetTerm())
+		logger.Info("receive new term on InstallSnapshot response, fallback to follower")
+
+		return
+	}
+
+	r.lastContact[peerId] = time.Now()
+
+	matchIndex := result.req.GetLastIncludedId()
+	if matchIndex < r.matchIndex[peerId] {
+		matchIndex = r.matchIndex[peerId]
+	}
+	nextIndex := matchIndex + 1
+	r.setNextAndMatchIndex(peerId, nextIndex, matchIndex)
+
+	// logs of the peer match the snapshot, send entries after it optimistically
+	r.getProgress(peerId).probe = false
+
+	logger.Info("install snapshot successfully, set next index and match index",
+		zap.Uint64("nextIndex", nextIndex),
+		zap.Uint64("matchIndex", matchIndex))
+}
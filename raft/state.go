@@ -1,11 +1,11 @@
 package raft
 
 import (
-	"bytes"
-	"encoding/gob"
+	"context"
 	"sync"
 
 	"github.com/justin0u0/raft/pb"
+	"google.golang.org/protobuf/proto"
 )
 
 type RaftState uint32
@@ -33,144 +33,464 @@ type raftState struct {
 	// raft state
 	state RaftState
 
-	// persistent state on all servers
+	// persistent state on all servers, logs are read from and written to the log store
 
-	currentTerm uint64
-	votedFor    uint32
-	logs        []*pb.Entry
+	currentTerm   uint64
+	votedFor      uint32
+	logStore      LogStore
+	stableStore   StableStore
+	snapshotStore SnapshotStore
+	// storage makes writes to the log store durable in batches
+	storage *storage
+
+	// logs before and including `lastIncludedId` are discarded and replaced by the snapshot
+
+	lastIncludedId            uint64
+	lastIncludedTerm          uint64
+	lastIncludedConfiguration *pb.Configuration
+	lastIncludedSessions      []*pb.Session
+
+	// the term and the vote in the stable store, they are saved only if changed
+
+	savedTerm     uint64
+	savedVotedFor uint32
 
 	// volatile state on all servers
 
 	commitIndex uint64
 	lastApplied uint64
+	// leaderId is the leader of the current term known by the server, zero if unknown
+	leaderId uint32
+	// appliedCh is closed and replaced whenever `lastApplied` advances
+	appliedCh chan struct{}
+	// pendingSnapshot is the installed snapshot waiting to be restored by the apply goroutine
+	pendingSnapshot *Snapshot
+
+	// client sessions after applying logs up to and including `lastApplied`,
+	// and results of commands in sessions that are applied but not compacted, indexed by the log id
+
+	sessions       map[uint64]*pb.Session
+	sessionResults map[uint64][]byte
+
+	// latest configuration in logs or in the snapshot and the log id of it
+
+	configuration   *pb.Configuration
+	configurationId uint64
 
 	// volatile state on leader
 
 	nextIndex  map[uint32]uint64
 	matchIndex map[uint32]uint64
+	// futures are commands waiting to be applied, indexed by the log id
+	futures map[uint64]*applyFuture
 
 	mu sync.Mutex
 }
 
 // persistence
 
-func (rs *raftState) saveRaftState(p Persister) error {
+// saveRaftState saves the term and the vote if they are changed since the last save,
+// and waits until logs written before are durable
+func (rs *raftState) saveRaftState(ctx context.Context) error {
 	rs.mu.Lock()
-	defer rs.mu.Unlock()
+	err := rs.saveState()
+	rs.mu.Unlock()
 
-	buf := bytes.Buffer{}
-	enc := gob.NewEncoder(&buf)
-	enc.Encode(rs.currentTerm)
-	enc.Encode(rs.votedFor)
-	enc.Encode(rs.logs)
+	if err != nil {
+		return err
+	}
+
+	return rs.storage.wait(ctx)
+}
+
+func (rs *raftState) saveState() error {
+	if rs.currentTerm == rs.savedTerm && rs.votedFor == rs.savedVotedFor {
+		return nil
+	}
 
-	if err := p.SaveRaftState(buf.Bytes()); err != nil {
+	if err := rs.stableStore.SetState(rs.currentTerm, rs.votedFor); err != nil {
 		return err
 	}
 
+	rs.savedTerm = rs.currentTerm
+	rs.savedVotedFor = rs.votedFor
+
 	return nil
 }
 
-func (rs *raftState) loadRaftState(p Persister) error {
+func (rs *raftState) loadRaftState() error {
 	rs.mu.Lock()
 	defer rs.mu.Unlock()
 
-	raftState, err := p.LoadRaftState()
+	currentTerm, votedFor, err := rs.stableStore.GetState()
 	if err != nil {
 		return err
 	}
 
-	if raftState != nil {
-		dec := gob.NewDecoder(bytes.NewBuffer(raftState))
-		dec.Decode(&rs.currentTerm)
-		dec.Decode(&rs.votedFor)
-		dec.Decode(&rs.logs)
+	metadata, _, err := rs.snapshotStore.LoadSnapshot()
+	if err != nil {
+		return err
 	}
 
+	rs.currentTerm = currentTerm
+	rs.votedFor = votedFor
+	rs.savedTerm = currentTerm
+	rs.savedVotedFor = votedFor
+
+	if metadata != nil {
+		rs.lastIncludedId = metadata.GetLastIncludedId()
+		rs.lastIncludedTerm = metadata.GetLastIncludedTerm()
+		rs.lastIncludedSessions = metadata.GetSessions()
+
+		if metadata.GetConfiguration() != nil {
+			rs.lastIncludedConfiguration = metadata.GetConfiguration()
+		}
+
+		// logs in the snapshot may be not deleted if the server crashes after saving the snapshot
+		if err := rs.discardLogs(rs.lastIncludedId, rs.lastIncludedTerm); err != nil {
+			return err
+		}
+	}
+
+	rs.storage.reset(rs.getLastLogId())
+	rs.resetConfiguration()
+
 	return nil
 }
 
 // getLastLog gets last log id and last log term and returns zero-values if not found
 func (rs *raftState) getLastLog() (id, term uint64) {
-	if len(rs.logs) == 0 {
-		return 0, 0
+	if log := rs.getLog(rs.getLastLogId()); log != nil {
+		return log.GetId(), log.GetTerm()
 	}
 
-	log := rs.logs[len(rs.logs)-1]
+	return rs.lastIncludedId, rs.lastIncludedTerm
+}
 
-	return log.GetId(), log.GetTerm()
+// getLastLogId gets the last log id in the log store or in the snapshot
+func (rs *raftState) getLastLogId() uint64 {
+	lastId, err := rs.logStore.LastIndex()
+	if err != nil || lastId < rs.lastIncludedId {
+		return rs.lastIncludedId
+	}
+
+	return lastId
 }
 
-// getLog gets the log by the given log id and returns nil if not found
+// getLog gets the log by the given log id and returns nil if not found or already compacted
 func (rs *raftState) getLog(id uint64) *pb.Entry {
-	logs := rs.getLogs(id)
-	if len(logs) != 0 {
-		return logs[0]
+	if id <= rs.lastIncludedId {
+		return nil
 	}
 
-	return nil
+	log, err := rs.logStore.GetLog(id)
+	if err != nil {
+		return nil
+	}
+
+	return log
 }
 
-// getLogs gets all logs from the start id to the end and returns empty list if not found
+// getLogTerm gets the term of the log by the given log id, including the last log in the snapshot,
+// and returns false if not found
+func (rs *raftState) getLogTerm(id uint64) (uint64, bool) {
+	if id == 0 {
+		return 0, true
+	}
+
+	if id == rs.lastIncludedId {
+		return rs.lastIncludedTerm, true
+	}
+
+	if log := rs.getLog(id); log != nil {
+		return log.GetTerm(), true
+	}
+
+	return 0, false
+}
+
+// findLastLogOfTerm finds the last log id with the given term and returns false if not found
+func (rs *raftState) findLastLogOfTerm(term uint64) (uint64, bool) {
+	for id := rs.getLastLogId(); id > rs.lastIncludedId; id-- {
+		log := rs.getLog(id)
+		if log == nil || log.GetTerm() < term {
+			return 0, false
+		}
+
+		if log.GetTerm() == term {
+			return id, true
+		}
+	}
+
+	if rs.lastIncludedId != 0 && rs.lastIncludedTerm == term {
+		return rs.lastIncludedId, true
+	}
+
+	return 0, false
+}
+
+// getLogs gets all logs from the start id to the end and returns empty list if not found,
+// logs that are already compacted are skipped
 func (rs *raftState) getLogs(startId uint64) []*pb.Entry {
-	if len(rs.logs) == 0 {
-		return []*pb.Entry{}
+	return rs.getLogsBetween(startId, rs.getLastLogId())
+}
+
+// getLogsBetween gets logs between the start id and the end id inclusive,
+// logs that are already compacted are skipped
+func (rs *raftState) getLogsBetween(startId, endId uint64) []*pb.Entry {
+	if startId <= rs.lastIncludedId {
+		startId = rs.lastIncludedId + 1
+	}
+
+	logs := []*pb.Entry{}
+	for id := startId; id <= endId; id++ {
+		log := rs.getLog(id)
+		if log == nil {
+			break
+		}
+
+		logs = append(logs, log)
 	}
 
-	lastLog := rs.logs[len(rs.logs)-1]
-	logIdDiff := int(lastLog.GetId() - startId)
-	if len(rs.logs)-1-logIdDiff < 0 {
-		return []*pb.Entry{}
+	return logs
+}
+
+// getLogsLimited gets at most `maxEntries` logs from the start id with the total size up to `maxSize`,
+// the first log is always included even if it is larger than `maxSize`
+func (rs *raftState) getLogsLimited(startId uint64, maxEntries int, maxSize int) []*pb.Entry {
+	logs := []*pb.Entry{}
+	size := 0
+
+	for _, log := range rs.getLogsBetween(startId, startId+uint64(maxEntries)-1) {
+		size += proto.Size(log)
+		if len(logs) != 0 && size > maxSize {
+			break
+		}
+
+		logs = append(logs, log)
 	}
 
-	return rs.logs[len(rs.logs)-1-logIdDiff:]
+	return logs
 }
 
-// appendLogs appends logs to the raft state
-func (rs *raftState) appendLogs(logs []*pb.Entry) {
+// appendLogs appends logs to the log store
+func (rs *raftState) appendLogs(logs []*pb.Entry) error {
 	rs.mu.Lock()
 	defer rs.mu.Unlock()
 
-	rs.logs = append(rs.logs, logs...)
+	// the term is saved before logs, so the saved term is never older than terms of saved logs
+	if err := rs.saveState(); err != nil {
+		return err
+	}
+
+	if err := rs.logStore.StoreLogs(logs); err != nil {
+		return err
+	}
+
+	rs.storage.write(rs.getLastLogId())
+
+	for i := len(logs) - 1; i >= 0; i-- {
+		if configuration := decodeConfiguration(logs[i]); configuration != nil {
+			rs.configuration = configuration
+			rs.configurationId = logs[i].GetId()
+			break
+		}
+	}
+
+	return nil
 }
 
 // deleteLogs deletes all logs after the given log id
-func (rs *raftState) deleteLogs(id uint64) {
+func (rs *raftState) deleteLogs(id uint64) error {
 	rs.mu.Lock()
 	defer rs.mu.Unlock()
 
-	index := -1
+	if id < rs.lastIncludedId {
+		id = rs.lastIncludedId
+	}
 
-	// find the smallest log index that is greater then or equal to the given log id
-	for i := len(rs.logs) - 1; i >= 0; i-- {
-		if rs.logs[i].GetId() >= id {
-			index = i
+	if lastId := rs.getLastLogId(); id < lastId {
+		if err := rs.logStore.DeleteRange(id+1, lastId); err != nil {
+			return err
 		}
+
+		rs.storage.write(id)
 	}
 
-	// deletes all logs after that log
-	if index != -1 {
-		rs.logs = rs.logs[:index+1]
+	// the latest configuration is deleted, fallback to the previous one
+	if rs.configurationId > id {
+		rs.resetConfiguration()
 	}
+
+	return nil
 }
 
-// applyLogs applies logs between (lastApplied, commitIndex]
-func (rs *raftState) applyLogs(applyCh chan<- *pb.Entry) {
+// compactLogs saves the snapshot and discards all logs before and including the given log id,
+// logs after it are retained only if the log with the given id has the given term
+func (rs *raftState) compactLogs(id, term uint64, configuration *pb.Configuration, sessions []*pb.Session, snapshot []byte) error {
 	rs.mu.Lock()
 	defer rs.mu.Unlock()
 
-	logs := rs.getLogs(rs.lastApplied + 1)
-	for _, log := range logs {
-		if log.GetId() > rs.commitIndex {
-			break
+	if id <= rs.lastIncludedId {
+		return nil
+	}
+
+	if err := rs.saveState(); err != nil {
+		return err
+	}
+
+	// logs are discarded after the snapshot is saved, so they are never lost if the server crashes in between
+	metadata := &pb.SnapshotMetadata{
+		LastIncludedId:   id,
+		LastIncludedTerm: term,
+		Configuration:    configuration,
+		Sessions:         sessions,
+	}
+
+	if err := rs.snapshotStore.SaveSnapshot(metadata, snapshot); err != nil {
+		return err
+	}
+
+	if err := rs.discardLogs(id, term); err != nil {
+		return err
+	}
+
+	rs.lastIncludedId = id
+	rs.lastIncludedTerm = term
+	rs.lastIncludedConfiguration = configuration
+	rs.lastIncludedSessions = sessions
+	rs.storage.write(rs.getLastLogId())
+
+	for logId := range rs.sessionResults {
+		if logId <= id {
+			delete(rs.sessionResults, logId)
+		}
+	}
+
+	rs.resetConfiguration()
+
+	return nil
+}
+
+// discardLogs deletes logs before and including the given log id from the log store,
+// logs after it are retained only if the log with the given id has the given term
+func (rs *raftState) discardLogs(id, term uint64) error {
+	firstId, err := rs.logStore.FirstIndex()
+	if err != nil {
+		return err
+	}
+
+	lastId, err := rs.logStore.LastIndex()
+	if err != nil {
+		return err
+	}
+
+	if firstId == 0 || firstId > id {
+		return nil
+	}
+
+	if log, err := rs.logStore.GetLog(id); err == nil && log.GetTerm() == term && lastId > id {
+		return rs.logStore.DeleteRange(firstId, id)
+	}
+
+	return rs.logStore.DeleteRange(firstId, lastId)
+}
+
+// resetConfiguration finds the latest configuration in logs or in the snapshot
+func (rs *raftState) resetConfiguration() {
+	rs.configuration, rs.configurationId = rs.getConfiguration(rs.getLastLogId())
+}
+
+// getConfiguration gets the latest configuration before or at the given log id and the log id of it
+func (rs *raftState) getConfiguration(id uint64) (*pb.Configuration, uint64) {
+	if lastId := rs.getLastLogId(); id > lastId {
+		id = lastId
+	}
+
+	for ; id > rs.lastIncludedId; id-- {
+		if configuration := decodeConfiguration(rs.getLog(id)); configuration != nil {
+			return configuration, id
+		}
+	}
+
+	return rs.lastIncludedConfiguration, rs.lastIncludedId
+}
+
+// decodeConfiguration decodes the configuration from the log and returns nil if it is not a configuration log
+func decodeConfiguration(log *pb.Entry) *pb.Configuration {
+	if log.GetType() != pb.EntryType_CONFIGURATION {
+		return nil
+	}
+
+	configuration := &pb.Configuration{}
+	if err := proto.Unmarshal(log.GetData(), configuration); err != nil {
+		return nil
+	}
+
+	return configuration
+}
+
+func (rs *raftState) addFuture(id uint64, future *applyFuture) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	rs.futures[id] = future
+}
+
+// failFutures fails futures of logs that are not committed yet
+func (rs *raftState) failFutures(err error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	for id, future := range rs.futures {
+		if id > rs.commitIndex {
+			future.fail(err)
+			delete(rs.futures, id)
 		}
+	}
+}
 
-		applyCh <- log
+// restoreSnapshot moves `commitIndex` to the snapshot, the snapshot is restored by the apply goroutine
+// before logs after it are applied
+func (rs *raftState) restoreSnapshot(snapshot *Snapshot) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	rs.commitIndex = snapshot.LastIncludedId
+	rs.pendingSnapshot = snapshot
+}
+
+func (rs *raftState) notifyApplied() {
+	close(rs.appliedCh)
+	rs.appliedCh = make(chan struct{})
+}
+
+// waitApplied waits until logs up to and including the given log id are applied
+func (rs *raftState) waitApplied(ctx context.Context, id uint64) error {
+	for {
+		rs.mu.Lock()
+		lastApplied, appliedCh := rs.lastApplied, rs.appliedCh
+		rs.mu.Unlock()
 
-		rs.lastApplied = log.GetId()
+		if lastApplied >= id {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-appliedCh:
+		}
 	}
 }
 
+func (rs *raftState) getLastApplied() uint64 {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	return rs.lastApplied
+}
+
 func (rs *raftState) toFollower(term uint64) {
 	rs.mu.Lock()
 	defer rs.mu.Unlock()
@@ -180,6 +500,7 @@ func (rs *raftState) toFollower(term uint64) {
 	if rs.currentTerm < term {
 		rs.currentTerm = term
 		rs.votedFor = 0
+		rs.leaderId = 0
 	}
 }
 
@@ -197,6 +518,13 @@ func (rs *raftState) toLeader() {
 	rs.state = Leader
 }
 
+func (rs *raftState) setLeader(id uint32) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	rs.leaderId = id
+}
+
 func (rs *raftState) voteFor(id uint32, voteForSelf bool) {
 	rs.mu.Lock()
 	defer rs.mu.Unlock()
@@ -204,6 +532,7 @@ func (rs *raftState) voteFor(id uint32, voteForSelf bool) {
 	// if vote for self, increase current term
 	if voteForSelf {
 		rs.currentTerm++
+		rs.leaderId = 0
 	}
 
 	rs.votedFor = id
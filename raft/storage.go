@@ -0,0 +1,207 @@
+package raft
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// StorageStats are statistics of fsyncs batched by the storage goroutine
+type StorageStats struct {
+	// Syncs is the number of fsyncs
+	Syncs uint64
+	// Writes is the number of log writes made durable by fsyncs
+	Writes uint64
+	// LastBatchSize is the number of log writes made durable by the latest fsync
+	LastBatchSize uint64
+	// MaxBatchSize is the largest number of log writes made durable by one fsync
+	MaxBatchSize uint64
+}
+
+// AverageBatchSize is the average number of log writes made durable by one fsync
+func (s StorageStats) AverageBatchSize() float64 {
+	if s.Syncs == 0 {
+		return 0
+	}
+
+	return float64(s.Writes) / float64(s.Syncs)
+}
+
+// StorageStats returns statistics of batched fsyncs
+func (r *Raft) StorageStats() StorageStats {
+	r.storage.mu.Lock()
+	defer r.storage.mu.Unlock()
+
+	return r.storage.stats
+}
+
+// storage is the group commit of the log store, writes are made in the state loop without waiting for fsync,
+// and the storage goroutine makes all pending writes durable with one fsync, so concurrent proposals and
+// incoming AppendEntries share fsyncs
+type storage struct {
+	// syncer is nil if writes to the log store are durable once they return
+	syncer Syncer
+	logger *zap.Logger
+
+	// writes is the number of writes, and lastLogId is the last log id after the latest write
+	writes    uint64
+	lastLogId uint64
+	// synced is the number of durable writes, and syncedLogId is the last durable log id
+	synced      uint64
+	syncedLogId uint64
+	// syncedCh is closed and replaced whenever `synced` advances or the storage fails
+	syncedCh chan struct{}
+	stats    StorageStats
+	// err is set once fsync fails, writes after the last successful fsync are never durable after that
+	err error
+
+	// notifyCh wakes up the storage goroutine when there are new writes
+	notifyCh chan struct{}
+	// durableCh notifies the state loop when `syncedLogId` advances
+	durableCh chan struct{}
+
+	mu sync.Mutex
+}
+
+func newStorage(logStore LogStore, logger *zap.Logger) *storage {
+	syncer, _ := logStore.(Syncer)
+
+	return &storage{
+		syncer:    syncer,
+		logger:    logger,
+		syncedCh:  make(chan struct{}),
+		notifyCh:  make(chan struct{}, 1),
+		durableCh: make(chan struct{}, 1),
+	}
+}
+
+// reset sets the last log id loaded from the log store, which is already durable
+func (s *storage) reset(lastLogId uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastLogId = lastLogId
+	s.syncedLogId = lastLogId
+}
+
+// write records a write to the log store, lastLogId is the last log id after the write
+func (s *storage) write(lastLogId uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.writes++
+	s.lastLogId = lastLogId
+
+	if s.syncer == nil {
+		s.synced = s.writes
+		s.syncedLogId = lastLogId
+		s.notifyDurable()
+		return
+	}
+
+	select {
+	case s.notifyCh <- struct{}{}:
+	default:
+	}
+}
+
+// wait waits until all previous writes are durable, it fails if the storage fails
+func (s *storage) wait(ctx context.Context) error {
+	s.mu.Lock()
+	writes := s.writes
+
+	for s.synced < writes {
+		if s.err != nil {
+			s.mu.Unlock()
+			return s.err
+		}
+
+		syncedCh := s.syncedCh
+		s.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return errRPCTimeout
+		case <-syncedCh:
+		}
+
+		s.mu.Lock()
+	}
+	s.mu.Unlock()
+
+	return nil
+}
+
+// getSyncedLogId gets the last durable log id
+func (s *storage) getSyncedLogId() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.syncedLogId
+}
+
+// run makes pending writes durable with one fsync until the context is done or fsync fails
+func (s *storage) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.notifyCh:
+		}
+
+		s.mu.Lock()
+		writes, lastLogId := s.writes, s.lastLogId
+		batchSize := writes - s.synced
+		s.mu.Unlock()
+
+		if batchSize == 0 {
+			continue
+		}
+
+		// writes after reading `writes` may be also synced, they are made durable by the next fsync anyway
+		if err := s.syncer.Sync(); err != nil {
+			// the failure is fatal, the OS may drop the dirty pages so a later fsync does not make them durable
+			s.logger.Error("fail to sync logs, stop the storage", zap.Error(err))
+			s.fail(fmt.Errorf("fail to sync logs: %w", err))
+			return
+		}
+
+		s.mu.Lock()
+		s.synced = writes
+		s.syncedLogId = lastLogId
+		s.stats.Syncs++
+		s.stats.Writes += batchSize
+		s.stats.LastBatchSize = batchSize
+		if batchSize > s.stats.MaxBatchSize {
+			s.stats.MaxBatchSize = batchSize
+		}
+
+		close(s.syncedCh)
+		s.syncedCh = make(chan struct{})
+		s.mu.Unlock()
+
+		s.logger.Debug("sync logs", zap.Uint64("batchSize", batchSize), zap.Uint64("syncedLogId", lastLogId))
+
+		s.notifyDurable()
+	}
+}
+
+// fail fails all waiters, and waiters in the future
+func (s *storage) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.err = err
+	close(s.syncedCh)
+	s.syncedCh = make(chan struct{})
+}
+
+// notifyDurable notifies the state loop without blocking, a pending notification covers later writes
+func (s *storage) notifyDurable() {
+	select {
+	case s.durableCh <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,136 @@
+package raft
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// slowSyncer is a log store whose fsync takes a while
+type slowSyncer struct {
+	*MemoryPersister
+}
+
+func (s *slowSyncer) Sync() error {
+	time.Sleep(10 * time.Millisecond)
+	return nil
+}
+
+func TestStorageGroupCommit(t *testing.T) {
+	s := newStorage(&slowSyncer{MemoryPersister: NewMemoryPersister()}, zap.NewNop())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	go s.run(ctx)
+
+	// concurrent writers wait for fsyncs at the same time
+	wg := sync.WaitGroup{}
+	for i := 1; i <= 20; i++ {
+		s.write(uint64(i))
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if err := s.wait(ctx); err != nil {
+				t.Errorf("fail to wait for writes to be durable: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if syncedLogId := s.getSyncedLogId(); syncedLogId != 20 {
+		t.Fatalf("expect log 20 is durable, got %d", syncedLogId)
+	}
+
+	s.mu.Lock()
+	stats := s.stats
+	s.mu.Unlock()
+
+	if stats.Writes != 20 {
+		t.Fatalf("expect 20 writes are synced, got %d", stats.Writes)
+	}
+
+	if stats.Syncs >= 20 || stats.MaxBatchSize < 2 {
+		t.Fatalf("expect writes are batched, got %d syncs with max batch size %d", stats.Syncs, stats.MaxBatchSize)
+	}
+}
+
+// failingSyncer is a log store whose fsync fails
+type failingSyncer struct {
+	*MemoryPersister
+	syncs int
+}
+
+func (s *failingSyncer) Sync() error {
+	s.syncs++
+	return errors.New("fsync failure")
+}
+
+func TestStorageSyncFailure(t *testing.T) {
+	syncer := &failingSyncer{MemoryPersister: NewMemoryPersister()}
+	s := newStorage(syncer, zap.NewNop())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	doneCh := make(chan struct{})
+	go func() {
+		defer close(doneCh)
+		s.run(ctx)
+	}()
+
+	s.write(1)
+	if err := s.wait(ctx); err == nil || errors.Is(err, errRPCTimeout) {
+		t.Fatalf("expect the waiter fails with the fsync error, got %v", err)
+	}
+
+	// the storage stops at the first failure, so later writes are never synced
+	select {
+	case <-doneCh:
+	case <-ctx.Done():
+		t.Fatal("expect the storage stops once fsync fails")
+	}
+
+	s.write(2)
+	if err := s.wait(ctx); err == nil || errors.Is(err, errRPCTimeout) {
+		t.Fatalf("expect later waiters fail with the fsync error, got %v", err)
+	}
+
+	if syncer.syncs != 1 {
+		t.Fatalf("expect fsync is not retried, got %d fsyncs", syncer.syncs)
+	}
+	if syncedLogId := s.getSyncedLogId(); syncedLogId != 0 {
+		t.Fatalf("expect no logs are durable, got %d", syncedLogId)
+	}
+}
+
+func TestStorageWithoutSyncer(t *testing.T) {
+	s := newStorage(NewMemoryPersister(), zap.NewNop())
+
+	// writes to a log store without Sync are durable once they return
+	s.write(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if err := s.wait(ctx); err != nil {
+		t.Fatalf("expect writes are durable, got %v", err)
+	}
+
+	if syncedLogId := s.getSyncedLogId(); syncedLogId != 1 {
+		t.Fatalf("expect log 1 is durable, got %d", syncedLogId)
+	}
+
+	// the leader is notified to count itself for the durable log
+	select {
+	case <-s.durableCh:
+	default:
+		t.Fatal("expect the state loop is notified once log 1 is durable")
+	}
+}
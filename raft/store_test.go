@@ -0,0 +1,20 @@
+package raft_test
+
+import (
+	"testing"
+
+	"github.com/justin0u0/raft/raft"
+	"github.com/justin0u0/raft/raft/storetest"
+)
+
+func TestMemoryPersisterConformance(t *testing.T) {
+	storetest.TestLogStore(t, func(t *testing.T) raft.LogStore { return raft.NewMemoryPersister() })
+	storetest.TestStableStore(t, func(t *testing.T) raft.StableStore { return raft.NewMemoryPersister() })
+	storetest.TestSnapshotStore(t, func(t *testing.T) raft.SnapshotStore { return raft.NewMemoryPersister() })
+}
+
+func TestFilePersisterConformance(t *testing.T) {
+	storetest.TestLogStore(t, func(t *testing.T) raft.LogStore { return raft.NewTestFilePersister(t, t.TempDir()) })
+	storetest.TestStableStore(t, func(t *testing.T) raft.StableStore { return raft.NewTestFilePersister(t, t.TempDir()) })
+	storetest.TestSnapshotStore(t, func(t *testing.T) raft.SnapshotStore { return raft.NewTestFilePersister(t, t.TempDir()) })
+}
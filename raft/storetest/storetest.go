@@ -0,0 +1,241 @@
+// Package storetest is a conformance test suite for LogStore, StableStore and SnapshotStore backends.
+package storetest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/justin0u0/raft/pb"
+	"github.com/justin0u0/raft/raft"
+)
+
+// TestLogStore runs the conformance tests of LogStore, newStore must return an empty store
+func TestLogStore(t *testing.T, newStore func(t *testing.T) raft.LogStore) {
+	t.Run("Empty", func(t *testing.T) {
+		s := newStore(t)
+
+		checkIndex(t, s, 0, 0)
+
+		if _, err := s.GetLog(1); !errors.Is(err, raft.ErrLogNotFound) {
+			t.Fatalf("expect ErrLogNotFound, got %v", err)
+		}
+	})
+
+	t.Run("StoreLogs", func(t *testing.T) {
+		s := newStore(t)
+
+		storeLogs(t, s, newLogs(1, 3, 1))
+		storeLogs(t, s, newLogs(4, 5, 2))
+
+		checkIndex(t, s, 1, 5)
+		checkLogs(t, s, 1, 1, 1, 2, 2)
+
+		if _, err := s.GetLog(6); !errors.Is(err, raft.ErrLogNotFound) {
+			t.Fatalf("expect ErrLogNotFound, got %v", err)
+		}
+	})
+
+	t.Run("StoreLogsReplaceConflicts", func(t *testing.T) {
+		s := newStore(t)
+
+		storeLogs(t, s, newLogs(1, 5, 1))
+		storeLogs(t, s, newLogs(3, 3, 2))
+
+		checkIndex(t, s, 1, 3)
+		checkLogs(t, s, 1, 1, 2)
+	})
+
+	t.Run("StoreLogsWithGap", func(t *testing.T) {
+		s := newStore(t)
+
+		storeLogs(t, s, newLogs(1, 3, 1))
+
+		if err := s.StoreLogs(newLogs(5, 5, 1)); err == nil {
+			t.Fatalf("expect an error when storing logs after a gap")
+		}
+
+		checkIndex(t, s, 1, 3)
+	})
+
+	t.Run("StoreLogsAfterSnapshot", func(t *testing.T) {
+		s := newStore(t)
+
+		// the first log of an empty store can have any id
+		storeLogs(t, s, newLogs(11, 12, 3))
+
+		checkIndex(t, s, 11, 12)
+		checkLogs(t, s, 3, 3)
+	})
+
+	t.Run("DeleteSuffix", func(t *testing.T) {
+		s := newStore(t)
+
+		storeLogs(t, s, newLogs(1, 5, 1))
+		deleteRange(t, s, 4, 5)
+
+		checkIndex(t, s, 1, 3)
+		checkLogs(t, s, 1, 1, 1)
+
+		storeLogs(t, s, newLogs(4, 4, 2))
+
+		checkIndex(t, s, 1, 4)
+		checkLogs(t, s, 1, 1, 1, 2)
+	})
+
+	t.Run("DeletePrefix", func(t *testing.T) {
+		s := newStore(t)
+
+		storeLogs(t, s, newLogs(1, 5, 1))
+		deleteRange(t, s, 1, 3)
+
+		checkIndex(t, s, 4, 5)
+		checkLogs(t, s, 1, 1)
+
+		if _, err := s.GetLog(3); !errors.Is(err, raft.ErrLogNotFound) {
+			t.Fatalf("expect ErrLogNotFound, got %v", err)
+		}
+
+		storeLogs(t, s, newLogs(6, 6, 2))
+
+		checkIndex(t, s, 4, 6)
+		checkLogs(t, s, 1, 1, 2)
+	})
+
+	t.Run("DeleteAll", func(t *testing.T) {
+		s := newStore(t)
+
+		storeLogs(t, s, newLogs(1, 5, 1))
+		deleteRange(t, s, 1, 5)
+
+		checkIndex(t, s, 0, 0)
+
+		storeLogs(t, s, newLogs(8, 8, 2))
+
+		checkIndex(t, s, 8, 8)
+	})
+}
+
+// TestStableStore runs the conformance tests of StableStore, newStore must return an empty store
+func TestStableStore(t *testing.T, newStore func(t *testing.T) raft.StableStore) {
+	s := newStore(t)
+
+	checkState(t, s, 0, 0)
+
+	if err := s.SetState(1, 2); err != nil {
+		t.Fatalf("fail to set state: %v", err)
+	}
+	checkState(t, s, 1, 2)
+
+	if err := s.SetState(2, 0); err != nil {
+		t.Fatalf("fail to set state: %v", err)
+	}
+	checkState(t, s, 2, 0)
+}
+
+// TestSnapshotStore runs the conformance tests of SnapshotStore, newStore must return an empty store
+func TestSnapshotStore(t *testing.T, newStore func(t *testing.T) raft.SnapshotStore) {
+	s := newStore(t)
+
+	if metadata, _, err := s.LoadSnapshot(); err != nil || metadata != nil {
+		t.Fatalf("expect no snapshot, got %v, %v", metadata, err)
+	}
+
+	for _, id := range []uint64{5, 10} {
+		metadata := &pb.SnapshotMetadata{
+			LastIncludedId:   id,
+			LastIncludedTerm: 1,
+			Configuration:    &pb.Configuration{Servers: []*pb.Server{{Id: 1, Address: "localhost:30001"}}},
+			Sessions:         []*pb.Session{{ClientId: 1, Sequence: 2, Result: []byte("result")}},
+		}
+
+		if err := s.SaveSnapshot(metadata, []byte("snapshot")); err != nil {
+			t.Fatalf("fail to save snapshot: %v", err)
+		}
+
+		loaded, data, err := s.LoadSnapshot()
+		if err != nil {
+			t.Fatalf("fail to load snapshot: %v", err)
+		}
+
+		if loaded.GetLastIncludedId() != id || loaded.GetLastIncludedTerm() != 1 {
+			t.Fatalf("expect the last included log %d with term 1, got %d with term %d",
+				id, loaded.GetLastIncludedId(), loaded.GetLastIncludedTerm())
+		}
+
+		if len(loaded.GetConfiguration().GetServers()) != 1 || len(loaded.GetSessions()) != 1 {
+			t.Fatalf("expect the configuration and sessions are saved")
+		}
+
+		if string(data) != "snapshot" {
+			t.Fatalf("expect snapshot %q, got %q", "snapshot", data)
+		}
+	}
+}
+
+// newLogs creates logs between the start id and the end id inclusive with the given term
+func newLogs(startId, endId, term uint64) []*pb.Entry {
+	logs := []*pb.Entry{}
+	for id := startId; id <= endId; id++ {
+		logs = append(logs, &pb.Entry{Id: id, Term: term, Data: []byte{byte(id)}})
+	}
+
+	return logs
+}
+
+func storeLogs(t *testing.T, s raft.LogStore, logs []*pb.Entry) {
+	t.Helper()
+
+	if err := s.StoreLogs(logs); err != nil {
+		t.Fatalf("fail to store logs: %v", err)
+	}
+}
+
+func deleteRange(t *testing.T, s raft.LogStore, min, max uint64) {
+	t.Helper()
+
+	if err := s.DeleteRange(min, max); err != nil {
+		t.Fatalf("fail to delete logs between %d and %d: %v", min, max, err)
+	}
+}
+
+func checkIndex(t *testing.T, s raft.LogStore, firstId, lastId uint64) {
+	t.Helper()
+
+	first, err := s.FirstIndex()
+	if err != nil || first != firstId {
+		t.Fatalf("expect first index %d, got %d, %v", firstId, first, err)
+	}
+
+	last, err := s.LastIndex()
+	if err != nil || last != lastId {
+		t.Fatalf("expect last index %d, got %d, %v", lastId, last, err)
+	}
+}
+
+// checkLogs checks terms of all logs from the first index
+func checkLogs(t *testing.T, s raft.LogStore, terms ...uint64) {
+	t.Helper()
+
+	first, _ := s.FirstIndex()
+	for i, term := range terms {
+		id := first + uint64(i)
+
+		log, err := s.GetLog(id)
+		if err != nil {
+			t.Fatalf("fail to get log %d: %v", id, err)
+		}
+
+		if log.GetId() != id || log.GetTerm() != term || len(log.GetData()) != 1 || log.GetData()[0] != byte(id) {
+			t.Fatalf("expect log %d with term %d, got log %d with term %d", id, term, log.GetId(), log.GetTerm())
+		}
+	}
+}
+
+func checkState(t *testing.T, s raft.StableStore, currentTerm uint64, votedFor uint32) {
+	t.Helper()
+
+	term, vote, err := s.GetState()
+	if err != nil || term != currentTerm || vote != votedFor {
+		t.Fatalf("expect term %d and vote %d, got term %d and vote %d, %v", currentTerm, votedFor, term, vote, err)
+	}
+}
@@ -0,0 +1,172 @@
+package raft
+
+import (
+	"context"
+	"time"
+
+	"github.com/justin0u0/raft/pb"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+)
+
+var (
+	errLeadershipTransferring       = newRPCError(codes.Unavailable, "leadership transfer is in progress")
+	errLeadershipTransferTimeout    = newRPCError(codes.DeadlineExceeded, "leadership transfer timeout")
+	errLeadershipTransferToNonVoter = newRPCError(codes.InvalidArgument, "leadership can only be transferred to a voter")
+	errLeadershipTransferFailed     = newRPCError(codes.Aborted, "leadership is taken by a server other than the target")
+)
+
+// leadershipTransfer is an in progress leadership transfer started on the leader,
+// it is kept after the leader steps down until the leader of the newer term is known
+type leadershipTransfer struct {
+	targetId uint32
+	// rpc is responded when the new leader is known or timeout
+	rpc *rpc
+	// timeoutNowSent is true if TimeoutNow is already sent to the target
+	timeoutNowSent bool
+	timeoutCh      <-chan time.Time
+}
+
+// RPC handlers
+
+// transferLeadership starts a leadership transfer, the rpc is responded after the new leader is known or timeout
+func (r *Raft) transferLeadership(rpc *rpc, req *pb.TransferLeadershipRequest) {
+	if r.state != Leader {
+		rpc.respond(nil, r.notLeaderError())
+		return
+	}
+
+	if r.leadershipTransfer != nil {
+		rpc.respond(nil, errLeadershipTransferring)
+		return
+	}
+
+	if req.GetId() == r.id {
+		rpc.respond(&pb.TransferLeadershipResponse{}, nil)
+		return
+	}
+
+	if !r.isVoter(req.GetId()) {
+		rpc.respond(nil, errLeadershipTransferToNonVoter)
+		return
+	}
+
+	r.leadershipTransfer = &leadershipTransfer{
+		targetId:  req.GetId(),
+		rpc:       rpc,
+		timeoutCh: time.After(r.config.ElectionTimeout),
+	}
+
+	r.logger.Info("start leadership transfer", zap.Uint32("target", req.GetId()))
+
+	// the target starts an election without waiting for the lease to expire
+	r.revokeLease()
+
+	// the target may be already up-to-date
+	r.sendTimeoutNowIfReady()
+}
+
+func (r *Raft) timeoutNow(req *pb.TimeoutNowRequest) (*pb.TimeoutNowResponse, error) {
+	if req.GetTerm() < r.currentTerm {
+		r.logger.Info("reject timeout now since current term is older")
+
+		return &pb.TimeoutNowResponse{Term: r.currentTerm}, nil
+	}
+
+	if req.GetTerm() > r.currentTerm {
+		r.toFollower(req.GetTerm())
+		r.logger.Info("increase term since receive a newer one", zap.Uint64("term", r.currentTerm))
+	}
+
+	// the target is up-to-date with the leader before TimeoutNow is sent, so it must know the leader of the term
+	if req.GetLeaderId() != r.leaderId {
+		r.logger.Info("reject timeout now since it is not sent by the current leader", zap.Uint32("leader", req.GetLeaderId()))
+
+		return &pb.TimeoutNowResponse{Term: r.currentTerm}, nil
+	}
+
+	if !r.isVoter(r.id) {
+		return nil, errLeadershipTransferToNonVoter
+	}
+
+	// start an election immediately without waiting for the heartbeat timeout
+	r.transferElection = true
+	r.toCandidate()
+
+	r.logger.Info("receive timeout now from leader, start election", zap.Uint32("leader", req.GetLeaderId()))
+
+	return &pb.TimeoutNowResponse{Term: r.currentTerm}, nil
+}
+
+// leader related
+
+// sendTimeoutNowIfReady sends TimeoutNow to the target once its logs are up-to-date
+func (r *Raft) sendTimeoutNowIfReady() {
+	transfer := r.leadershipTransfer
+	if transfer == nil || transfer.timeoutNowSent {
+		return
+	}
+
+	lastLogId, _ := r.getLastLog()
+	if r.matchIndex[transfer.targetId] < lastLogId {
+		return
+	}
+
+	peer, ok := r.peers[transfer.targetId]
+	if !ok {
+		return
+	}
+
+	transfer.timeoutNowSent = true
+
+	req := &pb.TimeoutNowRequest{Term: r.currentTerm, LeaderId: r.id}
+	targetId := transfer.targetId
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), r.config.ElectionTimeout)
+		defer cancel()
+
+		if _, err := peer.TimeoutNow(ctx, req); err != nil {
+			r.logger.Error("fail to send TimeoutNow RPC", zap.Error(err), zap.Uint32("peer", targetId))
+		}
+	}()
+
+	r.logger.Info("target is up-to-date, send timeout now", zap.Uint32("target", targetId))
+}
+
+func (r *Raft) handleLeadershipTransferTimeout() {
+	r.logger.Info("leadership transfer timeout", zap.Uint32("target", r.leadershipTransfer.targetId))
+
+	r.leadershipTransfer.rpc.respond(nil, errLeadershipTransferTimeout)
+	r.leadershipTransfer = nil
+}
+
+// finishLeadershipTransfer responds the in progress leadership transfer once the leader after the step down is known,
+// it succeeds only if the target becomes the leader
+func (r *Raft) finishLeadershipTransfer() {
+	transfer := r.leadershipTransfer
+	if transfer == nil || r.leaderId == 0 {
+		return
+	}
+
+	r.leadershipTransfer = nil
+
+	if r.leaderId != transfer.targetId {
+		r.logger.Info("leadership transfer failed", zap.Uint32("target", transfer.targetId), zap.Uint32("leader", r.leaderId))
+
+		transfer.rpc.respond(nil, errLeadershipTransferFailed)
+		return
+	}
+
+	r.logger.Info("leadership transfer finished", zap.Uint32("target", transfer.targetId))
+
+	transfer.rpc.respond(&pb.TransferLeadershipResponse{}, nil)
+}
+
+func (r *Raft) leadershipTransferTimeoutCh() <-chan time.Time {
+	if r.leadershipTransfer == nil {
+		return nil
+	}
+
+	return r.leadershipTransfer.timeoutCh
+}
@@ -15,3 +15,15 @@ func randomTimeout(minVal time.Duration) <-chan time.Time {
 
 	return time.After(minVal + extra)
 }
+
+// resetTimer resets the timer to fire after d, the stale expiration not received yet is dropped.
+func resetTimer(t *time.Timer, d time.Duration) {
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
+		}
+	}
+
+	t.Reset(d)
+}